@@ -0,0 +1,69 @@
+package geb
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"gorm.io/gorm"
+)
+
+// WithAdvisoryLock runs fn while holding the session-level advisory lock
+// key, waiting for other holders to release it first. The lock is held on
+// a connection taken out of the pool for the duration of fn.
+func (pg *PG) WithAdvisoryLock(ctx context.Context, key int64, fn func() error) error {
+	return withAdvisoryLock(ctx, pg.GormDB(), key, func(*gorm.DB) error {
+		return fn()
+	})
+}
+
+// TryAdvisoryLock runs fn only if the advisory lock key is free, and
+// reports whether it was. It never waits for the lock.
+func (pg *PG) TryAdvisoryLock(ctx context.Context, key int64, fn func() error) (bool, error) {
+	return lockSession(ctx, pg.GormDB(), key, true, func(*gorm.DB) error {
+		return fn()
+	})
+}
+
+// withAdvisoryLock runs fn on a single pinned connection while holding
+// pg_advisory_lock(key). Advisory locks belong to the session, so lock,
+// fn and unlock must all use the same connection.
+func withAdvisoryLock(ctx context.Context, db *gorm.DB, key int64, fn func(tx *gorm.DB) error) error {
+	_, err := lockSession(ctx, db, key, false, fn)
+	return err
+}
+
+// lockSession pins a connection, locks key on it and, if the lock was
+// acquired, runs fn on the same connection before unlocking. With try it
+// uses pg_try_advisory_lock instead of waiting.
+func lockSession(ctx context.Context, db *gorm.DB, key int64, try bool, fn func(tx *gorm.DB) error) (acquired bool, err error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if try {
+		if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil || !acquired {
+			return false, err
+		}
+	} else if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		return false, err
+	}
+	defer func() {
+		_, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", key)
+		if unlockErr != nil {
+			// Discard the session instead of returning it to the pool
+			// still holding the lock.
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+			if err == nil {
+				err = unlockErr
+			}
+		}
+	}()
+
+	return true, fn(pinConn(db.WithContext(ctx), conn))
+}
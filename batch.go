@@ -0,0 +1,51 @@
+package geb
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// maxBindParams is the most bind parameters Postgres accepts in one
+// statement.
+const maxBindParams = 65535
+
+// BatchError is returned by CreateInBatches when a batch fails. Rows in the
+// batches before it are committed; Inserted counts them, so a plain insert
+// can be resumed from that index of the slice.
+type BatchError struct {
+	Inserted int64
+	Err      error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("insert batch after %d rows: %v", e.Inserted, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// CreateInBatches inserts the slice value batchSize rows per statement. A
+// zero batchSize is derived from the model's column count so that no
+// statement exceeds the Postgres bind parameter limit. Each batch commits
+// on its own instead of the whole call running in one transaction, which
+// is what makes a failed insert resumable; for all-or-nothing, use GORM's
+// CreateInBatches on the tx of Transaction.
+func (pg *PG) CreateInBatches(ctx context.Context, value interface{}, batchSize int) error {
+	db := pg.GormDB().WithContext(ctx).Session(&gorm.Session{SkipDefaultTransaction: true})
+	if batchSize <= 0 {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(value); err != nil {
+			return err
+		}
+		batchSize = maxBindParams / max(len(stmt.Schema.DBNames), 1)
+	}
+
+	tx := db.CreateInBatches(value, batchSize)
+	if tx.Error != nil {
+		return &BatchError{Inserted: tx.RowsAffected, Err: tx.Error}
+	}
+	return nil
+}
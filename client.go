@@ -2,15 +2,83 @@ package geb
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
+	"errors"
+	"net"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Client is implemented by both PG and PGViaSSH, so code can depend on it
+// and switch between direct and tunneled connections.
+type Client interface {
+	Pinger
+	Close(ctx context.Context) error
+	GormDB() *gorm.DB
+}
+
+var errNoDB = errors.New("connection has no *gorm.DB")
+
+var (
+	_ Client = (*PG)(nil)
+	_ Client = (*PGViaSSH)(nil)
 )
 
 type PG struct {
+	// DB is kept for existing callers; prefer GormDB, as direct access to
+	// the field may go away in a future major version.
 	DB *gorm.DB
+
+	conf      ConnectConfig
+	replicas  []*sql.DB
+	tempFiles []string
+	stop      context.CancelFunc
+}
+
+// NewPG wraps a caller-built *gorm.DB without opening a new connection.
+// Close closes the pool behind db.
+func NewPG(db *gorm.DB) *PG {
+	return &PG{
+		DB: db,
+	}
+}
+
+// NewPGFromSQLDB opens GORM on top of a caller-configured *sql.DB, such as
+// one built with pgx through sql.OpenDB. Close closes sqldb.
+func NewPGFromSQLDB(sqldb *sql.DB) (*PG, error) {
+	db, err := gorm.Open(
+		postgres.New(postgres.Config{
+			Conn: sqldb,
+		}),
+		gormConfig(ConnectConfig{}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return NewPG(db), nil
+}
+
+func (pg *PG) GormDB() *gorm.DB {
+	return pg.DB
+}
+
+// WithContext returns the GORM handle bound to ctx, shorthand for
+// pg.GormDB().WithContext(ctx).
+func (pg *PG) WithContext(ctx context.Context) *gorm.DB {
+	return pg.GormDB().WithContext(ctx)
+}
+
+// SQLDB returns the *sql.DB behind the primary, e.g. to call Conn or hand
+// the pool to another library. Closing it closes pg.
+func (pg *PG) SQLDB() (*sql.DB, error) {
+	if pg.DB == nil {
+		return nil, errNoDB
+	}
+	return pg.DB.DB()
 }
 
 func (pg *PG) Ping(ctx context.Context) error {
@@ -20,71 +88,221 @@ func (pg *PG) Ping(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	err = sqlDB.Ping()
+	err = sqlDB.PingContext(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// Close closes the pool, the replicas and any temp SSL files. It gives up
+// waiting once ctx is done and returns its error; the close then finishes
+// in the background.
 func (pg *PG) Close(ctx context.Context) error {
+	if pg.stop != nil {
+		pg.stop()
+	}
 	sqlDB, err := pg.DB.
 		WithContext(ctx).
 		DB()
 	if err != nil {
 		return err
 	}
-	err = sqlDB.Close()
-	if err != nil {
+	start := time.Now()
+	err = closeContext(ctx, pg.DB.Logger, func() error {
+		err := sqlDB.Close()
+		for _, replica := range pg.replicas {
+			if rerr := replica.Close(); err == nil {
+				err = rerr
+			}
+		}
+		removeFiles(pg.tempFiles)
 		return err
+	})
+	emit(pg.conf.OnClose, pg.conf.connEvent(start, err))
+	return err
+}
+
+func (pg *PG) Stats() sql.DBStats {
+	sqlDB, err := pg.DB.DB()
+	if err != nil {
+		return sql.DBStats{}
 	}
-	return nil
+	return sqlDB.Stats()
+}
+
+// Config returns a copy of the config the connection was opened with, with
+// secrets masked.
+func (pg *PG) Config() ConnectConfig {
+	return pg.conf.redacted()
 }
 
 type ConnectConfig struct {
-	DBHost         string
-	DBPort         int
-	DBUser         string
-	DBPassword     string
-	DBName         string
-	MaxIdleCon     int
-	MaxOpenConns   int
-	EnableLogDebug bool
+	DBHost                       string
+	DBPort                       int
+	DBUser                       string
+	DBPassword                   string
+	PasswordProvider             func(ctx context.Context) (string, error)
+	DBName                       string
+	MaxIdleCon                   int
+	MaxOpenConns                 int
+	AutoTunePool                 bool
+	InstanceCount                int
+	EnableLogDebug               bool
+	SSLMode                      string
+	SSLRootCert                  string
+	SSLCert                      string
+	SSLKey                       string
+	ChannelBinding               string
+	SSLRootCertPEM               string
+	SSLCertPEM                   string
+	SSLKeyPEM                    string
+	AppName                      string
+	TimeZone                     string
+	SkipTimeZone                 bool
+	ConnectTimeout               time.Duration
+	ConnMaxLifetime              time.Duration
+	ConnMaxIdleTime              time.Duration
+	ConnMaxLifetimeJitter        time.Duration
+	DefaultQueryTimeout          time.Duration
+	PrepareStmt                  bool
+	NamingStrategy               schema.Namer
+	DisableFKConstraintOnMigrate bool
+	SkipDefaultTransaction       bool
+	DefaultIsolation             sql.IsolationLevel
+	Logger                       logger.Interface
+	LogLevel                     logger.LogLevel
+	Driver                       string
+	MigrationLockKey             int64
+	ReadReplicas                 []ConnectConfig
+	Hosts                        []HostPort
+	TargetSessionAttrs           string
+	StatementTimeout             time.Duration
+	IdleInTxTimeout              time.Duration
+	SearchPath                   string
+	ReadOnly                     bool
+	RuntimeParams                map[string]string
+	RawParams                    string
+	Plugins                      []gorm.Plugin
+	OnConnect                    func(ConnEvent)
+	OnClose                      func(ConnEvent)
+	DialFunc                     func(ctx context.Context, network, addr string) (net.Conn, error)
+	SOCKS5Proxy                  string
+	SOCKS5User                   string
+	SOCKS5Password               string
+}
+
+// HostPort is one candidate server of a multi-host connection.
+type HostPort struct {
+	Host string
+	Port int
+}
+
+const redactedValue = "***"
+
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+func (conf ConnectConfig) redacted() ConnectConfig {
+	conf.DBPassword = redact(conf.DBPassword)
+	conf.SSLKeyPEM = redact(conf.SSLKeyPEM)
+	conf.SOCKS5Password = redact(conf.SOCKS5Password)
+	if conf.ReadReplicas != nil {
+		replicas := make([]ConnectConfig, len(conf.ReadReplicas))
+		for i, replica := range conf.ReadReplicas {
+			replicas[i] = replica.redacted()
+		}
+		conf.ReadReplicas = replicas
+	}
+	return conf
 }
 
 func Connect(conf ConnectConfig) (*PG, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s application_name=xl_pgclient TimeZone=UTC",
-		conf.DBHost,
-		conf.DBPort,
-		conf.DBUser,
-		conf.DBPassword,
-		conf.DBName,
-	)
+	return ConnectContext(context.Background(), conf)
+}
 
-	logMode := logger.Silent
-	if conf.EnableLogDebug {
-		logMode = logger.Info
+func ConnectContext(ctx context.Context, conf ConnectConfig) (*PG, error) {
+	start := time.Now()
+	pg, err := connect(ctx, conf)
+	emit(conf.OnConnect, conf.connEvent(start, err))
+	return pg, err
+}
+
+func connect(ctx context.Context, conf ConnectConfig) (*PG, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	origConf := conf
+	tempFiles, err := writeSSLFiles(&conf)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn := BuildDSN(conf)
+
+	sqlDB, err := openSQLDB(conf, dsn)
+	if err != nil {
+		removeFiles(tempFiles)
+		return nil, redactError(connectError(ErrDBOpen, err), dsn, conf.DBPassword)
 	}
 
 	db, err := gorm.Open(
-		postgres.Open(dsn),
-		&gorm.Config{
-			Logger: logger.Default.LogMode(logMode),
-		},
+		postgres.New(postgres.Config{
+			Conn: sqlDB,
+		}),
+		gormConfig(conf),
 	)
 	if err != nil {
+		sqlDB.Close()
+		removeFiles(tempFiles)
+		return nil, redactError(connectError(ErrDBOpen, err), dsn, conf.DBPassword)
+	}
+
+	if err := usePlugins(db, conf); err != nil {
+		sqlDB.Close()
+		removeFiles(tempFiles)
 		return nil, err
 	}
 
-	sqlDB, err := db.DB()
+	configurePool(ctx, db, sqlDB, conf)
+	warnPrepareStmt(ctx, db, conf)
+
+	err = sqlDB.PingContext(ctx)
 	if err != nil {
-		return nil, err
+		sqlDB.Close()
+		removeFiles(tempFiles)
+		return nil, redactError(connectError(ErrDBPing, err), dsn, conf.DBPassword)
 	}
+	autoTunePool(ctx, db, sqlDB, conf)
 
-	sqlDB.SetMaxIdleConns(conf.MaxIdleCon)
-	sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
+	var replicas []*sql.DB
+	if len(conf.ReadReplicas) > 0 {
+		var replicaFiles []string
+		replicas, replicaFiles, err = useReplicas(ctx, db, conf)
+		if err != nil {
+			sqlDB.Close()
+			removeFiles(tempFiles)
+			return nil, err
+		}
+		tempFiles = append(tempFiles, replicaFiles...)
+	}
+
+	bgCtx, stop := context.WithCancel(context.Background())
+	jitterConnMaxLifetime(bgCtx, sqlDB, conf)
+	for i, replica := range replicas {
+		jitterConnMaxLifetime(bgCtx, replica, conf.replicaConfig(conf.ReadReplicas[i]))
+	}
 
 	return &PG{
-		DB: db,
+		DB:        db,
+		conf:      origConf,
+		replicas:  replicas,
+		tempFiles: tempFiles,
+		stop:      stop,
 	}, nil
 }
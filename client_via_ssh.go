@@ -4,23 +4,144 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/lib/pq"
 	"golang.org/x/crypto/ssh"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
 type PGViaSSH struct {
+	// DB and SSHCon are replaced by Reconnect; read DB through GormDB, which
+	// is safe to call concurrently with it.
 	DB     *gorm.DB
 	SSHCon *ssh.Client
+
+	mu            sync.RWMutex
+	conf          ConnectViaSSHConfig
+	jumpCons      []*ssh.Client
+	ownsSSHClient bool
+	driverName    string
+	stop          context.CancelFunc
+	health        *tunnelHealth
+	forward       net.Listener
+	closed        bool
+
+	extraMu  sync.Mutex
+	extraDBs []tunneledDB
+}
+
+// tunneledDB is a pool opened by OpenAnotherDB through the same SSH client.
+type tunneledDB struct {
+	db         *gorm.DB
+	driverName string
+}
+
+// defaultSSHDialTimeout bounds dialing and handshaking with each SSH hop
+// when SSHDialTimeout is unset.
+const defaultSSHDialTimeout = 15 * time.Second
+
+// ErrBorrowedSSHClient is returned by Reconnect on a connection opened with
+// ConnectViaExistingSSH.
+var ErrBorrowedSSHClient = errors.New("SSH client is owned by the caller and cannot be redialed by Reconnect")
+
+// ErrClosed is returned by Reconnect once Close has been called.
+var ErrClosed = errors.New("connection is closed")
+
+var sshDriverSeq atomic.Uint64
+
+// sshDrivers tracks the drivers registered for lib/pq tunnels. database/sql
+// has no way to unregister a driver, so every name stays in its table for
+// the life of the process; releaseSSHDriver at least drops the entry here
+// and the dialer's reference to its SSH client, leaving only an empty
+// dialer behind.
+var sshDrivers = struct {
+	sync.Mutex
+	dialers map[string]*ViaSSHDialer
+}{dialers: map[string]*ViaSSHDialer{}}
+
+func registerSSHDriver(client *ssh.Client) string {
+	name := fmt.Sprintf("postgres+ssh-%d", sshDriverSeq.Add(1))
+	dialer := &ViaSSHDialer{}
+	dialer.client.Store(client)
+	sql.Register(name, dialer)
+
+	sshDrivers.Lock()
+	sshDrivers.dialers[name] = dialer
+	sshDrivers.Unlock()
+
+	return name
+}
+
+func releaseSSHDriver(name string) {
+	sshDrivers.Lock()
+	dialer, ok := sshDrivers.dialers[name]
+	delete(sshDrivers.dialers, name)
+	sshDrivers.Unlock()
+
+	if ok {
+		dialer.client.Store(nil)
+	}
+}
+
+func sshDialer(name string) *ViaSSHDialer {
+	sshDrivers.Lock()
+	defer sshDrivers.Unlock()
+
+	return sshDrivers.dialers[name]
+}
+
+// DriverName returns the database/sql driver registered for this tunnel,
+// as passed to sql.Open, or "" with the pgx driver, which dials through
+// the tunnel without registering one. It changes on Reconnect.
+func (pg *PGViaSSH) DriverName() string {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
+	return pg.driverName
+}
+
+func (pg *PGViaSSH) GormDB() *gorm.DB {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
+	return pg.DB
+}
+
+// WithContext returns the GORM handle bound to ctx. Like GormDB, call it
+// per unit of work rather than keeping the result across a Reconnect.
+func (pg *PGViaSSH) WithContext(ctx context.Context) *gorm.DB {
+	return pg.GormDB().WithContext(ctx)
+}
+
+// SQLDB returns the *sql.DB behind the tunnel. Reconnect replaces it, so
+// don't hold on to it across reconnects.
+func (pg *PGViaSSH) SQLDB() (*sql.DB, error) {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
+	if pg.DB == nil {
+		return nil, errNoDB
+	}
+
+	return pg.DB.DB()
 }
 
 func (pg *PGViaSSH) Ping(ctx context.Context) error {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
 	sqlDB, err := pg.DB.
 		WithContext(ctx).
 		DB()
@@ -29,7 +150,7 @@ func (pg *PGViaSSH) Ping(ctx context.Context) error {
 		return err
 	}
 
-	err = sqlDB.Ping()
+	err = sqlDB.PingContext(ctx)
 
 	if err != nil {
 		return err
@@ -38,7 +159,24 @@ func (pg *PGViaSSH) Ping(ctx context.Context) error {
 	return nil
 }
 
+// Close closes the pool, the pools opened with OpenAnotherDB and, unless
+// the client was borrowed, the SSH tunnel. Each step gives up waiting once
+// ctx is done; closing the tunnel also fails any pool close still stuck on
+// it.
 func (pg *PGViaSSH) Close(ctx context.Context) error {
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	pg.closed = true
+
+	if pg.stop != nil {
+		pg.stop()
+	}
+
+	if pg.forward != nil {
+		pg.forward.Close()
+	}
+
 	sqlDB, err := pg.DB.
 		WithContext(ctx).
 		DB()
@@ -47,119 +185,798 @@ func (pg *PGViaSSH) Close(ctx context.Context) error {
 		return err
 	}
 
-	err = sqlDB.Close()
+	start := time.Now()
+	log, driverName := pg.DB.Logger, pg.driverName
 
-	if err != nil {
+	err = closeContext(ctx, log, func() error {
+		err := sqlDB.Close()
+		releaseSSHDriver(driverName)
+		pg.closeExtraDBs()
 		return err
+	})
+
+	if pg.ownsSSHClient {
+		sshCon, jumps := pg.SSHCon, pg.jumpCons
+
+		serr := closeContext(ctx, log, func() error {
+			return closeSSH(sshCon, jumps)
+		})
+
+		if err == nil {
+			err = serr
+		}
+	}
+
+	emit(pg.conf.OnClose, pg.conf.connEvent(start, err))
+
+	return err
+}
+
+func closeSSH(client *ssh.Client, jumps []*ssh.Client) error {
+	err := client.Close()
+
+	for i := len(jumps) - 1; i >= 0; i-- {
+		if jerr := jumps[i].Close(); err == nil {
+			err = jerr
+		}
 	}
 
-	err = pg.SSHCon.Close()
+	return err
+}
+
+// Config returns a copy of the config the connection was opened with, with
+// secrets masked.
+func (pg *PGViaSSH) Config() ConnectViaSSHConfig {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
+	return pg.conf.redacted()
+}
+
+func (pg *PGViaSSH) Stats() sql.DBStats {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
+	sqlDB, err := pg.DB.DB()
+
+	if err != nil {
+		return sql.DBStats{}
+	}
+
+	return sqlDB.Stats()
+}
+
+// Reconnect replaces a dropped SSH tunnel and its pool with fresh ones built
+// from the original config. The new connection is established before the
+// stale one is torn down, so a failed Reconnect leaves the handle as it was.
+func (pg *PGViaSSH) Reconnect(ctx context.Context) error {
+	pg.mu.RLock()
+	conf := pg.conf
+	pg.mu.RUnlock()
+
+	start := time.Now()
+	err := pg.reconnect(ctx)
+	emit(conf.OnReconnect, conf.connEvent(start, err))
+
+	return err
+}
+
+func (pg *PGViaSSH) reconnect(ctx context.Context) error {
+	pg.mu.RLock()
+	conf, owns, closed := pg.conf, pg.ownsSSHClient, pg.closed
+	pg.mu.RUnlock()
+
+	if closed {
+		return ErrClosed
+	}
+
+	if !owns {
+		return ErrBorrowedSSHClient
+	}
+
+	fresh, err := dialViaSSH(ctx, conf)
 
 	if err != nil {
 		return err
 	}
 
+	pg.mu.Lock()
+
+	// Close may have run while dialing; nobody would close fresh after it.
+	if pg.closed {
+		pg.mu.Unlock()
+		fresh.teardown()
+		return ErrClosed
+	}
+
+	stale := &PGViaSSH{DB: pg.DB, SSHCon: pg.SSHCon, jumpCons: pg.jumpCons, driverName: pg.driverName, stop: pg.stop}
+	pg.DB, pg.SSHCon, pg.jumpCons, pg.driverName, pg.stop, pg.health = fresh.DB, fresh.SSHCon, fresh.jumpCons, fresh.driverName, fresh.stop, fresh.health
+	pg.mu.Unlock()
+
+	stale.teardown()
+
+	// Pools from OpenAnotherDB dial through the stale tunnel and cannot
+	// be moved to the fresh one.
+	pg.closeExtraDBs()
+
 	return nil
 }
 
+// teardown closes the tunnel and pool of a connection replaced by, or
+// dialed for, Reconnect.
+func (pg *PGViaSSH) teardown() {
+	// Close the tunnel before the pool: sql.DB.Close waits for in-flight
+	// queries, which would otherwise hang on the dead tunnel.
+	if pg.stop != nil {
+		pg.stop()
+	}
+
+	closeSSH(pg.SSHCon, pg.jumpCons)
+
+	if sqlDB, err := pg.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	releaseSSHDriver(pg.driverName)
+}
+
 type ViaSSHDialer struct {
-	client *ssh.Client
+	client atomic.Pointer[ssh.Client]
 }
 
+var errSSHDriverReleased = errors.New("ssh tunnel for this driver has been closed")
+
 func (self *ViaSSHDialer) Open(s string) (_ driver.Conn, err error) {
 	return pq.DialOpen(self, s)
 }
 
 func (self *ViaSSHDialer) Dial(network, address string) (net.Conn, error) {
-	return self.client.Dial(network, address)
+	client := self.client.Load()
+
+	if client == nil {
+		return nil, errSSHDriverReleased
+	}
+
+	return client.Dial(network, address)
 }
 
 func (self *ViaSSHDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
-	return self.client.Dial(network, address)
+	client := self.client.Load()
+
+	if client == nil {
+		return nil, errSSHDriverReleased
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := client.DialContext(ctx, network, address)
+
+	if err != nil {
+		return nil, fmt.Errorf("dial %s through SSH tunnel: %w", address, err)
+	}
+
+	return conn, nil
 }
 
 type ConnectViaSSHConfig struct {
-	SSHHost       string
-	SSHPort       int
-	SSHUser       string
-	SSHPrivateKey string
-	DBHost        string
-	DBPort        int
-	DBUser        string
-	DBPassword    string
-	DBName        string
-	MaxIdleCon    int
-	MaxOpenConns  int
-	EnableLogDebug   bool
+	SSHHost                      string
+	SSHPort                      int
+	SSHUser                      string
+	SSHPrivateKey                string
+	SSHPrivateKeyPath            string
+	SSHPrivateKeyPassphrase      string
+	SSHCertificate               string
+	SSHPassword                  string
+	SSHUseAgent                  bool
+	SSHKeyboardInteractive       ssh.KeyboardInteractiveChallenge
+	SSHHostKeyCallback           ssh.HostKeyCallback
+	KnownHostsPath               string
+	SSHHostKeyFingerprint        string
+	InsecureSkipHostKeyVerify    bool
+	DBHost                       string
+	DBPort                       int
+	DBUser                       string
+	DBPassword                   string
+	PasswordProvider             func(ctx context.Context) (string, error)
+	DBName                       string
+	MaxIdleCon                   int
+	MaxOpenConns                 int
+	AutoTunePool                 bool
+	InstanceCount                int
+	EnableLogDebug               bool
+	SSLMode                      string
+	SSLRootCert                  string
+	SSLCert                      string
+	SSLKey                       string
+	ChannelBinding               string
+	AppName                      string
+	TimeZone                     string
+	SkipTimeZone                 bool
+	ConnectTimeout               time.Duration
+	ConnMaxLifetime              time.Duration
+	ConnMaxIdleTime              time.Duration
+	ConnMaxLifetimeJitter        time.Duration
+	DefaultQueryTimeout          time.Duration
+	PrepareStmt                  bool
+	NamingStrategy               schema.Namer
+	DisableFKConstraintOnMigrate bool
+	SkipDefaultTransaction       bool
+	DefaultIsolation             sql.IsolationLevel
+	SSHKeepAliveInterval         time.Duration
+	SSHDialTimeout               time.Duration
+	LocalForwardAddr             string
+	Logger                       logger.Interface
+	LogLevel                     logger.LogLevel
+	Driver                       string
+	Jumps                        []SSHHop
+	StatementTimeout             time.Duration
+	IdleInTxTimeout              time.Duration
+	SearchPath                   string
+	ReadOnly                     bool
+	RuntimeParams                map[string]string
+	RawParams                    string
+	Plugins                      []gorm.Plugin
+	OnConnect                    func(ConnEvent)
+	OnClose                      func(ConnEvent)
+	OnReconnect                  func(ConnEvent)
+}
+
+// SSHHop is a jump host traversed, in order, before SSHHost. Empty User
+// and auth fields fall back to SSHUser and the top-level auth methods; an
+// empty HostKeyFingerprint falls back to the top-level host key options.
+type SSHHop struct {
+	Host                 string
+	Port                 int
+	User                 string
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	Password             string
+	HostKeyFingerprint   string
+}
+
+func (conf ConnectViaSSHConfig) redacted() ConnectViaSSHConfig {
+	conf.SSHPrivateKey = redact(conf.SSHPrivateKey)
+	conf.SSHPrivateKeyPassphrase = redact(conf.SSHPrivateKeyPassphrase)
+	conf.SSHPassword = redact(conf.SSHPassword)
+	conf.DBPassword = redact(conf.DBPassword)
+
+	if conf.Jumps != nil {
+		jumps := make([]SSHHop, len(conf.Jumps))
+
+		for i, hop := range conf.Jumps {
+			hop.PrivateKey = redact(hop.PrivateKey)
+			hop.PrivateKeyPassphrase = redact(hop.PrivateKeyPassphrase)
+			hop.Password = redact(hop.Password)
+			jumps[i] = hop
+		}
+
+		conf.Jumps = jumps
+	}
+
+	return conf
+}
+
+func (conf ConnectViaSSHConfig) connectConfig() ConnectConfig {
+	return ConnectConfig{
+		DBHost:                       conf.DBHost,
+		DBPort:                       conf.DBPort,
+		DBUser:                       conf.DBUser,
+		DBPassword:                   conf.DBPassword,
+		PasswordProvider:             conf.PasswordProvider,
+		DBName:                       conf.DBName,
+		MaxIdleCon:                   conf.MaxIdleCon,
+		MaxOpenConns:                 conf.MaxOpenConns,
+		AutoTunePool:                 conf.AutoTunePool,
+		InstanceCount:                conf.InstanceCount,
+		EnableLogDebug:               conf.EnableLogDebug,
+		SSLMode:                      conf.SSLMode,
+		SSLRootCert:                  conf.SSLRootCert,
+		SSLCert:                      conf.SSLCert,
+		SSLKey:                       conf.SSLKey,
+		ChannelBinding:               conf.ChannelBinding,
+		AppName:                      conf.AppName,
+		TimeZone:                     conf.TimeZone,
+		SkipTimeZone:                 conf.SkipTimeZone,
+		ConnectTimeout:               conf.ConnectTimeout,
+		ConnMaxLifetime:              conf.ConnMaxLifetime,
+		ConnMaxIdleTime:              conf.ConnMaxIdleTime,
+		ConnMaxLifetimeJitter:        conf.ConnMaxLifetimeJitter,
+		DefaultQueryTimeout:          conf.DefaultQueryTimeout,
+		PrepareStmt:                  conf.PrepareStmt,
+		NamingStrategy:               conf.NamingStrategy,
+		DisableFKConstraintOnMigrate: conf.DisableFKConstraintOnMigrate,
+		SkipDefaultTransaction:       conf.SkipDefaultTransaction,
+		DefaultIsolation:             conf.DefaultIsolation,
+		Logger:                       conf.Logger,
+		LogLevel:                     conf.LogLevel,
+		Driver:                       conf.Driver,
+		StatementTimeout:             conf.StatementTimeout,
+		IdleInTxTimeout:              conf.IdleInTxTimeout,
+		SearchPath:                   conf.SearchPath,
+		ReadOnly:                     conf.ReadOnly,
+		RuntimeParams:                conf.RuntimeParams,
+		RawParams:                    conf.RawParams,
+		Plugins:                      conf.Plugins,
+		OnConnect:                    conf.OnConnect,
+		OnClose:                      conf.OnClose,
+	}
+}
+
+// viaSSHConfig is the inverse of connectConfig, for connections over a
+// borrowed SSH client. Settings ConnectViaSSHConfig has no field for are
+// dropped.
+func (conf ConnectConfig) viaSSHConfig() ConnectViaSSHConfig {
+	return ConnectViaSSHConfig{
+		DBHost:                       conf.DBHost,
+		DBPort:                       conf.DBPort,
+		DBUser:                       conf.DBUser,
+		DBPassword:                   conf.DBPassword,
+		PasswordProvider:             conf.PasswordProvider,
+		DBName:                       conf.DBName,
+		MaxIdleCon:                   conf.MaxIdleCon,
+		MaxOpenConns:                 conf.MaxOpenConns,
+		AutoTunePool:                 conf.AutoTunePool,
+		InstanceCount:                conf.InstanceCount,
+		EnableLogDebug:               conf.EnableLogDebug,
+		SSLMode:                      conf.SSLMode,
+		SSLRootCert:                  conf.SSLRootCert,
+		SSLCert:                      conf.SSLCert,
+		SSLKey:                       conf.SSLKey,
+		ChannelBinding:               conf.ChannelBinding,
+		AppName:                      conf.AppName,
+		TimeZone:                     conf.TimeZone,
+		SkipTimeZone:                 conf.SkipTimeZone,
+		ConnectTimeout:               conf.ConnectTimeout,
+		ConnMaxLifetime:              conf.ConnMaxLifetime,
+		ConnMaxIdleTime:              conf.ConnMaxIdleTime,
+		ConnMaxLifetimeJitter:        conf.ConnMaxLifetimeJitter,
+		DefaultQueryTimeout:          conf.DefaultQueryTimeout,
+		PrepareStmt:                  conf.PrepareStmt,
+		NamingStrategy:               conf.NamingStrategy,
+		DisableFKConstraintOnMigrate: conf.DisableFKConstraintOnMigrate,
+		SkipDefaultTransaction:       conf.SkipDefaultTransaction,
+		DefaultIsolation:             conf.DefaultIsolation,
+		Logger:                       conf.Logger,
+		LogLevel:                     conf.LogLevel,
+		Driver:                       conf.Driver,
+		StatementTimeout:             conf.StatementTimeout,
+		IdleInTxTimeout:              conf.IdleInTxTimeout,
+		SearchPath:                   conf.SearchPath,
+		ReadOnly:                     conf.ReadOnly,
+		RuntimeParams:                conf.RuntimeParams,
+		RawParams:                    conf.RawParams,
+		Plugins:                      conf.Plugins,
+		OnConnect:                    conf.OnConnect,
+		OnClose:                      conf.OnClose,
+	}
 }
 
 func ConnectViaSSH(conf ConnectViaSSHConfig) (*PGViaSSH, error) {
+	return ConnectViaSSHContext(context.Background(), conf)
+}
 
-	signer, err := ssh.ParsePrivateKey([]byte(conf.SSHPrivateKey))
+func ConnectViaSSHContext(ctx context.Context, conf ConnectViaSSHConfig) (*PGViaSSH, error) {
+	start := time.Now()
+	pg, err := connectViaSSH(ctx, conf)
+	emit(conf.OnConnect, conf.connEvent(start, err))
+
+	return pg, err
+}
+
+func connectViaSSH(ctx context.Context, conf ConnectViaSSHConfig) (*PGViaSSH, error) {
+	pg, err := dialViaSSH(ctx, conf)
 
 	if err != nil {
 		return nil, err
 	}
 
-	sshConfig := &ssh.ClientConfig{
-		User: conf.SSHUser,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	if conf.LocalForwardAddr != "" {
+		err = pg.listenLocalForward(conf.LocalForwardAddr)
+
+		if err != nil {
+			// Not a Close of a connection the caller ever saw.
+			pg.conf.OnClose = nil
+			pg.Close(ctx)
+			return nil, err
+		}
 	}
 
-	sshcon, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", conf.SSHHost, conf.SSHPort), sshConfig)
+	return pg, nil
+}
+
+// dialViaSSH is ConnectViaSSHContext without the local forward, which
+// Reconnect keeps across tunnels.
+func dialViaSSH(ctx context.Context, conf ConnectViaSSHConfig) (*PGViaSSH, error) {
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	if conf.SSHPrivateKey != "" && conf.SSHPrivateKeyPath != "" {
+		newLogger(conf.connectConfig()).Warn(ctx, "both SSHPrivateKey and SSHPrivateKeyPath are set, using SSHPrivateKey and ignoring %s", conf.SSHPrivateKeyPath)
+	}
+
+	sshcon, jumps, err := dialSSHChain(ctx, conf)
 
 	if err != nil {
 		return nil, err
 	}
 
-	sql.Register("postgres+ssh", &ViaSSHDialer{sshcon})
+	db, driverName, err := openViaSSH(ctx, conf.connectConfig(), sshcon)
 
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s application_name=xl_pgclient TimeZone=UTC",
-		conf.DBHost,
-		conf.DBPort,
-		conf.DBUser,
-		conf.DBPassword,
-		conf.DBName,
-	)
+	if err != nil {
+		closeSSH(sshcon, jumps)
+		return nil, err
+	}
 
-	sqldb, err := sql.Open("postgres+ssh", dsn)
+	bgCtx, stop := context.WithCancel(context.Background())
+
+	health := &tunnelHealth{}
+
+	if conf.SSHKeepAliveInterval > 0 {
+		go keepAlive(bgCtx, sshcon, conf.SSHKeepAliveInterval, health)
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		jitterConnMaxLifetime(bgCtx, sqlDB, conf.connectConfig())
+	}
+
+	return &PGViaSSH{
+		DB:            db,
+		SSHCon:        sshcon,
+		conf:          conf,
+		jumpCons:      jumps,
+		ownsSSHClient: true,
+		driverName:    driverName,
+		stop:          stop,
+		health:        health,
+	}, nil
+}
+
+// ConnectViaExistingSSH opens the database through an SSH client owned by
+// the caller. Close leaves the client open, and Reconnect is not
+// supported: redialing the tunnel is up to its owner.
+func ConnectViaExistingSSH(client *ssh.Client, conf ConnectConfig) (*PGViaSSH, error) {
+	return ConnectViaExistingSSHContext(context.Background(), client, conf)
+}
+
+func ConnectViaExistingSSHContext(ctx context.Context, client *ssh.Client, conf ConnectConfig) (*PGViaSSH, error) {
+	start := time.Now()
+	pg, err := connectViaExistingSSH(ctx, client, conf)
+	emit(conf.OnConnect, conf.connEvent(start, err))
+
+	return pg, err
+}
+
+func connectViaExistingSSH(ctx context.Context, client *ssh.Client, conf ConnectConfig) (*PGViaSSH, error) {
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := checkTunneledConfig(conf); err != nil {
+		return nil, err
+	}
+
+	db, driverName, err := openViaSSH(ctx, conf, client)
 
 	if err != nil {
 		return nil, err
 	}
 
-	logMode := logger.Silent
-	if conf.EnableLogDebug {
-		logMode = logger.Info
+	bgCtx, stop := context.WithCancel(context.Background())
+
+	if sqlDB, err := db.DB(); err == nil {
+		jitterConnMaxLifetime(bgCtx, sqlDB, conf)
+	}
+
+	return &PGViaSSH{
+		DB:         db,
+		SSHCon:     client,
+		conf:       conf.viaSSHConfig(),
+		driverName: driverName,
+		stop:       stop,
+		health:     &tunnelHealth{},
+	}, nil
+}
+
+// openViaSSH opens and pings a pool whose connections are dialed through
+// client. It never closes client.
+func openViaSSH(ctx context.Context, conf ConnectConfig, client *ssh.Client) (*gorm.DB, string, error) {
+	dsn := BuildDSN(conf)
+
+	sqldb, driverName, err := openSQLDBViaSSH(conf, dsn, client)
+
+	if err != nil {
+		return nil, "", redactError(connectError(ErrDBOpen, err), dsn, conf.DBPassword)
 	}
 
 	db, err := gorm.Open(
 		postgres.New(postgres.Config{
 			Conn: sqldb,
 		}),
-		&gorm.Config{
-			Logger: logger.Default.LogMode(logMode),
-		},
+		gormConfig(conf),
 	)
 
 	if err != nil {
+		sqldb.Close()
+		releaseSSHDriver(driverName)
+		return nil, "", redactError(connectError(ErrDBOpen, err), dsn, conf.DBPassword)
+	}
+
+	err = usePlugins(db, conf)
+
+	if err != nil {
+		sqldb.Close()
+		releaseSSHDriver(driverName)
+		return nil, "", err
+	}
+
+	if driverName != "" {
+		db.Logger.Info(ctx, "registered database/sql driver %s for the SSH tunnel", driverName)
+	}
+
+	configurePool(ctx, db, sqldb, conf)
+	warnPrepareStmt(ctx, db, conf)
+
+	err = sqldb.PingContext(ctx)
+
+	if err != nil {
+		sqldb.Close()
+		releaseSSHDriver(driverName)
+		return nil, "", redactError(connectError(ErrDBPing, err), dsn, conf.DBPassword)
+	}
+
+	autoTunePool(ctx, db, sqldb, conf)
+
+	return db, driverName, nil
+}
+
+func keepAlive(ctx context.Context, client *ssh.Client, interval time.Duration, health *tunnelHealth) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !health.probe(ctx, client) {
+				return
+			}
+		}
+	}
+}
+
+// openSQLDBViaSSH opens a pool whose connections are dialed through client.
+// pgx takes the dial function directly; lib/pq needs a registered driver,
+// whose name is returned.
+func openSQLDBViaSSH(conf ConnectConfig, dsn string, client *ssh.Client) (*sql.DB, string, error) {
+	if conf.Driver == DriverPGX {
+		connConfig, err := pgx.ParseConfig(dsn)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		// Leave name resolution to the far side of the tunnel.
+		connConfig.LookupFunc = func(ctx context.Context, host string) ([]string, error) {
+			return []string{host}, nil
+		}
+		connConfig.DialFunc = client.DialContext
+
+		return stdlib.OpenDB(*connConfig, pgxOptions(conf)...), "", nil
+	}
+
+	driverName := registerSSHDriver(client)
+
+	var sqldb *sql.DB
+	var err error
+
+	if conf.PasswordProvider != nil {
+		sqldb, err = openPQWithPasswordProvider(conf, dsn, sshDialer(driverName))
+	} else {
+		sqldb, err = sql.Open(driverName, dsn)
+	}
+
+	if err != nil {
+		releaseSSHDriver(driverName)
+		return nil, "", err
+	}
+
+	return sqldb, driverName, nil
+}
+
+// dialSSHChain connects to SSHHost through conf.Jumps and returns the
+// final client along with the jump clients it was dialed through.
+func dialSSHChain(ctx context.Context, conf ConnectViaSSHConfig) (*ssh.Client, []*ssh.Client, error) {
+	auth, releaseAuth, err := sshAuthMethods(conf)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer releaseAuth()
+
+	hostKeyCallback, err := hostKeyCallback(conf)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hops := append(slices.Clone(conf.Jumps), SSHHop{Host: conf.SSHHost, Port: conf.SSHPort})
+
+	timeout := conf.SSHDialTimeout
+
+	if timeout <= 0 {
+		timeout = defaultSSHDialTimeout
+	}
+
+	var d net.Dialer
+	dial := d.DialContext
+	var clients []*ssh.Client
+
+	for _, hop := range hops {
+		sshConfig := &ssh.ClientConfig{
+			User:            conf.SSHUser,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         timeout,
+		}
+
+		if hop.User != "" {
+			sshConfig.User = hop.User
+		}
+
+		if hop.HostKeyFingerprint != "" {
+			sshConfig.HostKeyCallback = fingerprintCallback(hop.HostKeyFingerprint)
+		}
+
+		if hop.PrivateKey != "" || hop.Password != "" {
+			hopAuth, releaseHopAuth, err := sshAuthMethods(ConnectViaSSHConfig{
+				SSHPrivateKey:           hop.PrivateKey,
+				SSHPrivateKeyPassphrase: hop.PrivateKeyPassphrase,
+				SSHPassword:             hop.Password,
+			})
+
+			if err != nil {
+				closeSSHClients(clients)
+				return nil, nil, err
+			}
+
+			defer releaseHopAuth()
+			sshConfig.Auth = hopAuth
+		}
+
+		addr := fmt.Sprintf("%s:%d", hop.Host, hop.Port)
+		client, err := dialSSH(ctx, dial, addr, sshConfig)
+
+		if err != nil {
+			closeSSHClients(clients)
+			return nil, nil, fmt.Errorf("%w %s: %w", ErrSSHDial, addr, err)
+		}
+
+		clients = append(clients, client)
+		dial = client.DialContext
+	}
+
+	last := len(clients) - 1
+
+	return clients[last], clients[:last], nil
+}
+
+func closeSSHClients(clients []*ssh.Client) {
+	if len(clients) > 0 {
+		last := len(clients) - 1
+		closeSSH(clients[last], clients[:last])
+	}
+}
+
+// dialSSH is ssh.Dial over the given dial function, with the dial and the
+// handshake bounded by ctx and config.Timeout.
+func dialSSH(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	parent := ctx
+
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
+	// Tell a bastion that doesn't answer apart from a caller giving up.
+	timedOut := func(err error) error {
+		if parent.Err() == nil && ctx.Err() != nil {
+			return fmt.Errorf("timed out after %s: %w", config.Timeout, err)
+		}
+
+		return err
+	}
+
+	conn, err := dial(ctx, "tcp", addr)
+
+	if err != nil {
+		return nil, timedOut(err)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+
+	if !stop() {
+		if err == nil {
+			c.Close()
+		}
+		return nil, timedOut(ctx.Err())
+	}
+
+	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	sqlDB, err := db.DB()
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func checkTunneledConfig(conf ConnectConfig) error {
+	errs := checkTunneledDriver(conf)
+
+	if len(conf.ReadReplicas) > 0 || conf.SSLRootCertPEM != "" || conf.SSLCertPEM != "" || conf.SSLKeyPEM != "" {
+		errs = append(errs, errors.New("ReadReplicas and inline SSL PEM fields are not supported through an SSH tunnel"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// OpenAnotherDB opens an additional pool, typically to another database or
+// port on the far side, through the existing SSH client. Close closes it
+// along with the tunnel; Reconnect closes it as well, so reopen it
+// afterwards.
+func (pg *PGViaSSH) OpenAnotherDB(conf ConnectConfig) (*gorm.DB, error) {
+	return pg.OpenAnotherDBContext(context.Background(), conf)
+}
+
+func (pg *PGViaSSH) OpenAnotherDBContext(ctx context.Context, conf ConnectConfig) (*gorm.DB, error) {
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := checkTunneledConfig(conf); err != nil {
+		return nil, err
+	}
+
+	// Held for the whole open so Close and Reconnect cannot pull the
+	// tunnel out from under it.
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
+	db, driverName, err := openViaSSH(ctx, conf, pg.SSHCon)
 
 	if err != nil {
 		return nil, err
 	}
 
-	sqlDB.SetMaxIdleConns(conf.MaxIdleCon)
-	sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
+	pg.extraMu.Lock()
+	pg.extraDBs = append(pg.extraDBs, tunneledDB{db: db, driverName: driverName})
+	pg.extraMu.Unlock()
 
-	return &PGViaSSH{
-		DB:     db,
-		SSHCon: sshcon,
-	}, nil
+	return db, nil
+}
+
+func (pg *PGViaSSH) closeExtraDBs() {
+	pg.extraMu.Lock()
+	defer pg.extraMu.Unlock()
+
+	for _, extra := range pg.extraDBs {
+		if sqlDB, err := extra.db.DB(); err == nil {
+			sqlDB.Close()
+		}
+
+		releaseSSHDriver(extra.driverName)
+	}
+
+	pg.extraDBs = nil
 }
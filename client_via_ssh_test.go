@@ -0,0 +1,101 @@
+package geb
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// localSSHClient returns an SSH client connected to a local server that
+// accepts any client and rejects every channel.
+func localSSHClient(t *testing.T) *ssh.Client {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConf := &ssh.ServerConfig{NoClientAuth: true}
+	serverConf.AddHostKey(signer)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		_, chans, reqs, err := ssh.NewServerConn(conn, serverConf)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for ch := range chans {
+			ch.Reject(ssh.Prohibited, "no channels")
+		}
+	}()
+
+	client, err := ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{
+		User:            "app",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestReconnectAfterClose(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectClose()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), gormConfig(ConnectConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing listens on SSHPort, so a Reconnect that dials fails with
+	// ErrSSHDial instead of ErrClosed.
+	pg := &PGViaSSH{
+		DB:            db,
+		SSHCon:        localSSHClient(t),
+		ownsSSHClient: true,
+		conf: ConnectViaSSHConfig{
+			SSHHost:                   "127.0.0.1",
+			SSHPort:                   closedPort(t),
+			SSHUser:                   "app",
+			SSHPassword:               "secret",
+			InsecureSkipHostKeyVerify: true,
+			DBHost:                    "127.0.0.1",
+			DBPort:                    5432,
+			DBUser:                    "app",
+			DBName:                    "orders",
+		},
+	}
+
+	if err := pg.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.Reconnect(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Reconnect after Close = %v, want ErrClosed", err)
+	}
+	if pg.GormDB() != db {
+		t.Error("Reconnect after Close replaced the pool")
+	}
+}
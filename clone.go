@@ -0,0 +1,41 @@
+package geb
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+)
+
+var errNoConfig = errors.New("connection was not opened from a ConnectConfig")
+
+// With returns a copy of conf modified by overrides. The slices and the
+// RuntimeParams map are copied too, so overrides can change them without
+// affecting conf.
+func (conf ConnectConfig) With(overrides func(*ConnectConfig)) ConnectConfig {
+	conf.ReadReplicas = slices.Clone(conf.ReadReplicas)
+	conf.Hosts = slices.Clone(conf.Hosts)
+	conf.Plugins = slices.Clone(conf.Plugins)
+	conf.RuntimeParams = maps.Clone(conf.RuntimeParams)
+	overrides(&conf)
+	return conf
+}
+
+// CloneTo opens a new connection with the config of pg but to the database
+// dbname, replicas included. Both connections must be closed separately.
+func (pg *PG) CloneTo(dbname string) (*PG, error) {
+	return pg.CloneToContext(context.Background(), dbname)
+}
+
+func (pg *PG) CloneToContext(ctx context.Context, dbname string) (*PG, error) {
+	// NewPG and NewPGFromSQLDB don't keep a config.
+	if pg.conf.DBUser == "" {
+		return nil, errNoConfig
+	}
+	return ConnectContext(ctx, pg.conf.With(func(conf *ConnectConfig) {
+		conf.DBName = dbname
+		for i := range conf.ReadReplicas {
+			conf.ReadReplicas[i].DBName = dbname
+		}
+	}))
+}
@@ -0,0 +1,30 @@
+package geb
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm/logger"
+)
+
+// closeContext runs close in the background and waits for it until ctx is
+// done. A close that outlives ctx keeps running, and its error is logged
+// through log instead of returned.
+func closeContext(ctx context.Context, log logger.Interface, close func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		go func() {
+			if err := <-done; err != nil {
+				log.Error(context.Background(), "close finished after its context was done: %v", err)
+			}
+		}()
+		return fmt.Errorf("close: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,103 @@
+package geb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+)
+
+// CopyFrom bulk loads rows into table with COPY FROM STDIN, which is much
+// faster than INSERT for large volumes. table may be schema-qualified as
+// "schema.table"; both it and columns are quoted as identifiers. The load
+// is atomic and returns the number of rows copied.
+func (pg *PG) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	sqlDB, err := pg.GormDB().DB()
+	if err != nil {
+		return 0, err
+	}
+	return copyFrom(ctx, sqlDB, table, columns, rows)
+}
+
+// CopyFrom is PG.CopyFrom through the SSH tunnel.
+func (pg *PGViaSSH) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error) {
+	sqlDB, err := pg.GormDB().DB()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return copyFrom(ctx, sqlDB, table, columns, rows)
+}
+
+func copyFrom(ctx context.Context, sqlDB *sql.DB, table string, columns []string, rows [][]interface{}) (int64, error) {
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return 0, fmt.Errorf("copy into %s: row %d has %d values, want %d", table, i, len(row), len(columns))
+		}
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var copied int64
+	usePQ := false
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			usePQ = true
+			return nil
+		}
+		n, err := c.Conn().CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+		copied = n
+		return err
+	})
+	if err != nil || !usePQ {
+		return copied, err
+	}
+	return copyInPQ(ctx, conn, table, columns, rows)
+}
+
+// copyInPQ loads rows with lib/pq's CopyIn, which streams COPY through a
+// prepared statement inside a transaction.
+func copyInPQ(ctx context.Context, conn *sql.Conn, table string, columns []string, rows [][]interface{}) (int64, error) {
+	query := pq.CopyIn(table, columns...)
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		query = pq.CopyInSchema(schema, name, columns...)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			return 0, err
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return 0, err
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int64(len(rows)), nil
+}
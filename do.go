@@ -0,0 +1,42 @@
+package geb
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Do runs fn on a connection taken out of the primary's pool. If fn fails
+// because that connection broke in a way that guarantees the failed
+// statement had no effect, such as the server terminating the session
+// during a failover, the connection is discarded and fn runs once more on
+// a fresh one. Errors from the statements themselves, and connections
+// dropped while a statement may have been executing, are returned as is.
+// Only the failing statement is known to have had no effect, so fn should
+// issue a single statement or wrap its statements in a transaction.
+func (pg *PG) Do(ctx context.Context, fn func(db *gorm.DB) error) error {
+	err := pg.doOnce(ctx, fn)
+	if err == nil || ctx.Err() != nil || !isRetryableConnError(err) {
+		return err
+	}
+	return pg.doOnce(ctx, fn)
+}
+
+func (pg *PG) doOnce(ctx context.Context, fn func(db *gorm.DB) error) error {
+	sqlDB, err := pg.GormDB().DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(pinConn(pg.GormDB().WithContext(ctx), conn))
+	if isConnError(err) {
+		discardConn(conn)
+		return err
+	}
+	conn.Close()
+	return err
+}
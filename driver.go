@@ -0,0 +1,115 @@
+package geb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+	"golang.org/x/net/proxy"
+)
+
+const (
+	DriverPGX = "pgx"
+	DriverPQ  = "pq"
+)
+
+var drivers = []string{DriverPGX, DriverPQ}
+
+func openSQLDB(conf ConnectConfig, dsn string) (*sql.DB, error) {
+	if conf.Driver == DriverPQ {
+		if conf.PasswordProvider != nil {
+			return openPQWithPasswordProvider(conf, dsn, nil)
+		}
+		return sql.Open("postgres", dsn)
+	}
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if conf.DialFunc != nil {
+		connConfig.DialFunc = conf.DialFunc
+	}
+	if conf.SOCKS5Proxy != "" {
+		dialFunc, err := socks5DialFunc(conf)
+		if err != nil {
+			return nil, err
+		}
+		// Leave name resolution to the proxy, like the SSH tunnel does.
+		connConfig.LookupFunc = func(ctx context.Context, host string) ([]string, error) {
+			return []string{host}, nil
+		}
+		connConfig.DialFunc = dialFunc
+	}
+	return stdlib.OpenDB(*connConfig, pgxOptions(conf)...), nil
+}
+
+// pgxOptions hooks PasswordProvider into pgx's BeforeConnect, so every new
+// physical connection authenticates with a fresh password.
+func pgxOptions(conf ConnectConfig) []stdlib.OptionOpenDB {
+	if conf.PasswordProvider == nil {
+		return nil
+	}
+	provider := conf.PasswordProvider
+	return []stdlib.OptionOpenDB{stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+		password, err := provider(ctx)
+		if err != nil {
+			return fmt.Errorf("PasswordProvider: %w", err)
+		}
+		cc.Password = password
+		return nil
+	})}
+}
+
+// openPQWithPasswordProvider is the lib/pq counterpart of pgxOptions. dialer
+// is nil for direct connections.
+func openPQWithPasswordProvider(conf ConnectConfig, dsn string, dialer pq.Dialer) (*sql.DB, error) {
+	// Fail on a malformed DSN now rather than on first use.
+	if _, err := pq.NewConnector(dsn); err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(pqPasswordConnector{dsn: dsn, provider: conf.PasswordProvider, dialer: dialer}), nil
+}
+
+type pqPasswordConnector struct {
+	dsn      string
+	provider func(ctx context.Context) (string, error)
+	dialer   pq.Dialer
+}
+
+func (c pqPasswordConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	password, err := c.provider(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("PasswordProvider: %w", err)
+	}
+	// lib/pq lets the last occurrence of a keyword win.
+	connector, err := pq.NewConnector(c.dsn + " password=" + quoteDSNValue(password))
+	if err != nil {
+		return nil, err
+	}
+	if c.dialer != nil {
+		connector.Dialer(c.dialer)
+	}
+	return connector.Connect(ctx)
+}
+
+func (c pqPasswordConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+func socks5DialFunc(conf ConnectConfig) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	var auth *proxy.Auth
+	if conf.SOCKS5User != "" {
+		auth = &proxy.Auth{User: conf.SOCKS5User, Password: conf.SOCKS5Password}
+	}
+	dialer, err := proxy.SOCKS5("tcp", conf.SOCKS5Proxy, auth, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.(proxy.ContextDialer).DialContext, nil
+}
@@ -0,0 +1,83 @@
+package geb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DryRun checks everything Connect would before dialing: the config is
+// validated, the DSN is built and parsed, and inline SSL PEM material is
+// decoded. It never touches the network, so it can run in CI against
+// production secrets. SSL files referenced by path are not read, as they
+// are often only mounted at runtime.
+func (conf ConnectConfig) DryRun() error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+	return conf.dryRun()
+}
+
+// DryRun is ConnectConfig.DryRun plus parsing the SSH private keys, of the
+// jump hosts included, and loading KnownHostsPath. Neither the SSH server
+// nor ssh-agent is contacted.
+func (conf ConnectViaSSHConfig) DryRun() error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	errs := []error{conf.connectConfig().dryRun()}
+	if _, err := privateKeySigner(conf); err != nil {
+		errs = append(errs, err)
+	}
+	for i, hop := range conf.Jumps {
+		_, err := privateKeySigner(ConnectViaSSHConfig{
+			SSHPrivateKey:           hop.PrivateKey,
+			SSHPrivateKeyPassphrase: hop.PrivateKeyPassphrase,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Jumps[%d]: %w", i, err))
+		}
+	}
+	if _, err := hostKeyCallback(conf); err != nil {
+		errs = append(errs, fmt.Errorf("KnownHostsPath: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (conf ConnectConfig) dryRun() error {
+	var errs []error
+	if err := checkSSLPEM(conf); err != nil {
+		errs = append(errs, err)
+	}
+
+	dsnConf := conf
+	dsnConf.SSLRootCert, dsnConf.SSLCert, dsnConf.SSLKey = "", "", ""
+	dsn := BuildDSN(dsnConf)
+	if _, err := pgconn.ParseConfig(dsn); err != nil {
+		errs = append(errs, redactError(fmt.Errorf("invalid DSN: %w", err), dsn, conf.DBPassword))
+	}
+
+	for i, replica := range conf.ReadReplicas {
+		if err := conf.replicaConfig(replica).dryRun(); err != nil {
+			errs = append(errs, fmt.Errorf("ReadReplicas[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkSSLPEM(conf ConnectConfig) error {
+	var errs []error
+	if conf.SSLRootCertPEM != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(conf.SSLRootCertPEM)) {
+		errs = append(errs, errors.New("SSLRootCertPEM contains no valid certificate"))
+	}
+	if conf.SSLCertPEM != "" && conf.SSLKeyPEM != "" {
+		if _, err := tls.X509KeyPair([]byte(conf.SSLCertPEM), []byte(conf.SSLKeyPEM)); err != nil {
+			errs = append(errs, fmt.Errorf("SSLCertPEM and SSLKeyPEM: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,221 @@
+package geb
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	defaultAppName  = "xl_pgclient"
+	defaultTimeZone = "UTC"
+
+	defaultConnectTimeout = 10 * time.Second
+)
+
+// BuildDSN returns the libpq keyword/value connection string Connect uses
+// for conf, with the same defaults applied. Inline PEM material
+// (SSLRootCertPEM and friends) is not included, as Connect only turns it
+// into sslrootcert/sslcert/sslkey file paths at connect time.
+func BuildDSN(conf ConnectConfig) string {
+	appName := conf.AppName
+	if appName == "" {
+		appName = defaultAppName
+	}
+	timeZone := conf.TimeZone
+	if timeZone == "" {
+		timeZone = defaultTimeZone
+	}
+	connectTimeout := conf.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+
+	host, port := conf.DBHost, strconv.Itoa(conf.DBPort)
+	if len(conf.Hosts) > 0 {
+		hosts := make([]string, len(conf.Hosts))
+		ports := make([]string, len(conf.Hosts))
+		for i, hp := range conf.Hosts {
+			hosts[i], ports[i] = hp.Host, strconv.Itoa(hp.Port)
+		}
+		host, port = strings.Join(hosts, ","), strings.Join(ports, ",")
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s application_name=%s",
+		quoteDSNValue(host),
+		port,
+		quoteDSNValue(conf.DBUser),
+		quoteDSNValue(conf.DBPassword),
+		quoteDSNValue(conf.DBName),
+		quoteDSNValue(appName),
+	)
+	if !conf.SkipTimeZone {
+		dsn += fmt.Sprintf(" TimeZone=%s", quoteDSNValue(timeZone))
+	}
+	dsn += fmt.Sprintf(" connect_timeout=%d", timeoutSeconds(connectTimeout))
+	if conf.SSLMode != "" {
+		dsn += fmt.Sprintf(" sslmode=%s", quoteDSNValue(conf.SSLMode))
+	}
+	if conf.SSLRootCert != "" {
+		dsn += fmt.Sprintf(" sslrootcert=%s", quoteDSNValue(conf.SSLRootCert))
+	}
+	if conf.SSLCert != "" {
+		dsn += fmt.Sprintf(" sslcert=%s", quoteDSNValue(conf.SSLCert))
+	}
+	if conf.SSLKey != "" {
+		dsn += fmt.Sprintf(" sslkey=%s", quoteDSNValue(conf.SSLKey))
+	}
+	if conf.ChannelBinding != "" {
+		dsn += fmt.Sprintf(" channel_binding=%s", quoteDSNValue(conf.ChannelBinding))
+	}
+	if conf.TargetSessionAttrs != "" {
+		dsn += fmt.Sprintf(" target_session_attrs=%s", quoteDSNValue(conf.TargetSessionAttrs))
+	}
+	if options := sessionOptions(conf); len(options) > 0 {
+		dsn += fmt.Sprintf(" options=%s", quoteDSNValue(strings.Join(options, " ")))
+	}
+	// Last, so libpq and pgx let a keyword repeated there win.
+	if raw := strings.TrimSpace(conf.RawParams); raw != "" {
+		dsn += " " + raw
+	}
+	return dsn
+}
+
+// BuildViaSSHDSN returns the connection string ConnectViaSSH uses for the
+// database behind the tunnel. DBHost and DBPort are resolved on the SSH
+// server, not locally.
+func BuildViaSSHDSN(conf ConnectViaSSHConfig) string {
+	return BuildDSN(conf.connectConfig())
+}
+
+// sessionOptions returns the -c flags sent in the startup packet's options
+// parameter, which set the parameters for every session of the pool.
+func sessionOptions(conf ConnectConfig) []string {
+	var options []string
+	if conf.StatementTimeout > 0 {
+		options = append(options, fmt.Sprintf("-c statement_timeout=%d", timeoutMillis(conf.StatementTimeout)))
+	}
+	if conf.IdleInTxTimeout > 0 {
+		options = append(options, fmt.Sprintf("-c idle_in_transaction_session_timeout=%d", timeoutMillis(conf.IdleInTxTimeout)))
+	}
+	if conf.SearchPath != "" {
+		options = append(options, fmt.Sprintf("-c search_path=%s", strings.Join(splitSearchPath(conf.SearchPath), ",")))
+	}
+	if conf.ReadOnly {
+		options = append(options, "-c default_transaction_read_only=on")
+	}
+	for _, key := range slices.Sorted(maps.Keys(conf.RuntimeParams)) {
+		options = append(options, fmt.Sprintf("-c %s=%s", key, optionEscaper.Replace(conf.RuntimeParams[key])))
+	}
+	return options
+}
+
+// optionEscaper escapes the backslashes and spaces the server would
+// otherwise read as argument separators in the options parameter.
+var optionEscaper = strings.NewReplacer(`\`, `\\`, " ", `\ `)
+
+func splitSearchPath(searchPath string) []string {
+	schemas := strings.Split(searchPath, ",")
+	for i, schema := range schemas {
+		schemas[i] = strings.TrimSpace(schema)
+	}
+	return schemas
+}
+
+// timeoutMillis rounds up to whole milliseconds, the unit of Postgres
+// timeout settings.
+func timeoutMillis(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// timeoutSeconds rounds up to whole seconds, as libpq only accepts integers.
+func timeoutSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+func checkTimeZone(tz string) error {
+	if tz == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("invalid TimeZone %q: %w", tz, err)
+	}
+	return nil
+}
+
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// quoteDSNValue quotes a value for a libpq keyword/value connection string.
+// Every value goes through it, as an unquoted password with a space or a
+// quote would otherwise silently shift the keywords after it.
+func quoteDSNValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n\r'\\") {
+		return v
+	}
+	return "'" + dsnEscaper.Replace(v) + "'"
+}
+
+var dsnPassword = regexp.MustCompile(`password=('(?:[^'\\]|\\.)*'|\S*)`)
+
+// redactDSN masks the password of a keyword/value connection string.
+func redactDSN(dsn string) string {
+	return dsnPassword.ReplaceAllString(dsn, "password="+redactedValue)
+}
+
+// dsnUserinfo matches the password of a postgres:// URL's userinfo.
+var dsnUserinfo = regexp.MustCompile(`(://[^:/@\s]*):[^@\s]*@`)
+
+// minBarePasswordLen is the shortest password masked wherever it appears
+// in an error message; shorter ones, like "a" or "postgres", would mangle
+// host, user and database names that happen to contain them.
+const minBarePasswordLen = 12
+
+// redactedError masks the DSN's password in err's message, while keeping
+// err reachable for errors.Is and errors.As. Only password= keywords and
+// URL userinfo are masked, plus the bare password if it is long enough to
+// be unlikely to appear elsewhere.
+type redactedError struct {
+	err      error
+	dsn      string
+	password string
+}
+
+func (e *redactedError) Error() string {
+	msg := strings.ReplaceAll(e.err.Error(), e.dsn, redactDSN(e.dsn))
+	// pgconn masks the password in its parse errors itself, but stops at
+	// the first escaped quote, so its copy of the DSN is swapped for ours.
+	var parseErr *pgconn.ParseConfigError
+	if errors.As(e.err, &parseErr) {
+		if rest, ok := strings.CutPrefix(parseErr.Error(), "cannot parse `"); ok {
+			if end := strings.Index(rest, "`: "); end >= 0 {
+				msg = strings.ReplaceAll(msg, rest[:end], redactDSN(parseErr.ConnString))
+			}
+		}
+	}
+	msg = redactDSN(msg)
+	msg = dsnUserinfo.ReplaceAllString(msg, "${1}:"+redactedValue+"@")
+	if len(e.password) >= minBarePasswordLen {
+		msg = strings.ReplaceAll(msg, e.password, redactedValue)
+	}
+	return msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError wraps err, if any, so that neither dsn nor password can leak
+// into logs through its message.
+func redactError(err error, dsn, password string) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{err: err, dsn: dsn, password: password}
+}
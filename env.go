@@ -0,0 +1,99 @@
+package geb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ConnectConfigFromEnv reads the libpq environment variables (PGHOST,
+// PGPORT, PGUSER, PGPASSWORD, PGDATABASE, ...) plus PG_MAX_IDLE_CONN and
+// PG_MAX_OPEN_CONN for the pool.
+func ConnectConfigFromEnv() (ConnectConfig, error) {
+	e := envReader{}
+	conf := ConnectConfig{
+		DBHost:       e.required("PGHOST"),
+		DBPort:       e.int("PGPORT", defaultPort),
+		DBUser:       e.required("PGUSER"),
+		DBPassword:   os.Getenv("PGPASSWORD"),
+		DBName:       e.required("PGDATABASE"),
+		MaxIdleCon:   e.int("PG_MAX_IDLE_CONN", 0),
+		MaxOpenConns: e.int("PG_MAX_OPEN_CONN", 0),
+		SSLMode:      os.Getenv("PGSSLMODE"),
+		SSLRootCert:  os.Getenv("PGSSLROOTCERT"),
+		SSLCert:      os.Getenv("PGSSLCERT"),
+		SSLKey:       os.Getenv("PGSSLKEY"),
+		AppName:      os.Getenv("PGAPPNAME"),
+		TimeZone:     os.Getenv("PGTZ"),
+	}
+	conf.ConnectTimeout = time.Duration(e.int("PGCONNECT_TIMEOUT", 0)) * time.Second
+	return conf, e.err()
+}
+
+// ConnectViaSSHConfigFromEnv reads the database settings like
+// ConnectConfigFromEnv and the tunnel settings from SSH_HOST, SSH_PORT,
+// SSH_USER, SSH_PRIVATE_KEY, SSH_PRIVATE_KEY_PATH, SSH_PRIVATE_KEY_PASSPHRASE,
+// SSH_CERTIFICATE, SSH_PASSWORD, SSH_KNOWN_HOSTS and SSH_HOST_KEY_FINGERPRINT.
+func ConnectViaSSHConfigFromEnv() (ConnectViaSSHConfig, error) {
+	db, dbErr := ConnectConfigFromEnv()
+
+	e := envReader{}
+	conf := ConnectViaSSHConfig{
+		SSHHost:                 e.required("SSH_HOST"),
+		SSHPort:                 e.int("SSH_PORT", 22),
+		SSHUser:                 e.required("SSH_USER"),
+		SSHPrivateKey:           os.Getenv("SSH_PRIVATE_KEY"),
+		SSHPrivateKeyPath:       os.Getenv("SSH_PRIVATE_KEY_PATH"),
+		SSHPrivateKeyPassphrase: os.Getenv("SSH_PRIVATE_KEY_PASSPHRASE"),
+		SSHCertificate:          os.Getenv("SSH_CERTIFICATE"),
+		SSHPassword:             os.Getenv("SSH_PASSWORD"),
+		KnownHostsPath:          os.Getenv("SSH_KNOWN_HOSTS"),
+		SSHHostKeyFingerprint:   os.Getenv("SSH_HOST_KEY_FINGERPRINT"),
+		DBHost:                  db.DBHost,
+		DBPort:                  db.DBPort,
+		DBUser:                  db.DBUser,
+		DBPassword:              db.DBPassword,
+		DBName:                  db.DBName,
+		MaxIdleCon:              db.MaxIdleCon,
+		MaxOpenConns:            db.MaxOpenConns,
+		SSLMode:                 db.SSLMode,
+		SSLRootCert:             db.SSLRootCert,
+		SSLCert:                 db.SSLCert,
+		SSLKey:                  db.SSLKey,
+		AppName:                 db.AppName,
+		TimeZone:                db.TimeZone,
+		ConnectTimeout:          db.ConnectTimeout,
+	}
+	return conf, errors.Join(dbErr, e.err())
+}
+
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) required(key string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		e.errs = append(e.errs, fmt.Errorf("environment variable %s is required", key))
+	}
+	return v
+}
+
+func (e *envReader) int(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("environment variable %s must be an integer, got %q", key, v))
+		return def
+	}
+	return n
+}
+
+func (e *envReader) err() error {
+	return errors.Join(e.errs...)
+}
@@ -0,0 +1,29 @@
+package geb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Connection errors wrap their cause, so both can be matched with errors.Is
+// and errors.As, e.g. to retry ErrSSHDial and ErrDBPing but fail hard on
+// ErrSSHKeyParse.
+var (
+	ErrSSHKeyParse = errors.New("parse SSH private key")
+	ErrSSHDial     = errors.New("dial SSH server")
+	ErrDBOpen      = errors.New("open database")
+	ErrDBPing      = errors.New("ping database")
+
+	// ErrTooManyClients is wrapped alongside ErrDBOpen or ErrDBPing when
+	// the server is at max_connections (SQLSTATE 53300).
+	ErrTooManyClients = errors.New("server has too many clients")
+)
+
+// connectError wraps err, a failure to open or ping the database, in
+// sentinel, adding ErrTooManyClients when the server reports it.
+func connectError(sentinel, err error) error {
+	if pgErrorCode(err) == sqlStateTooManyConnections {
+		return fmt.Errorf("%w: %w: %w", sentinel, ErrTooManyClients, err)
+	}
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
@@ -0,0 +1,44 @@
+package geb_test
+
+import (
+	"context"
+	"log"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+
+	"github.com/cans-communication/geb"
+)
+
+type Event struct {
+	ID   int64
+	Kind string
+}
+
+// Plugins registers dbresolver directly, here to send the events and
+// page_views tables to an analytics database while reads of every other
+// table go to a replica.
+func ExampleConnect_plugins() {
+	pg, err := geb.Connect(geb.ConnectConfig{
+		DBHost:     "db.internal",
+		DBPort:     5432,
+		DBUser:     "app",
+		DBPassword: "secret",
+		DBName:     "orders",
+		Plugins: []gorm.Plugin{
+			dbresolver.Register(dbresolver.Config{
+				Replicas: []gorm.Dialector{postgres.Open("host=replica.internal user=app dbname=orders")},
+				Policy:   dbresolver.RoundRobinPolicy(),
+			}).Register(dbresolver.Config{
+				Sources: []gorm.Dialector{postgres.Open("host=analytics.internal user=app dbname=analytics")},
+			}, &Event{}, "page_views"),
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer pg.Close(context.Background())
+
+	pg.WithContext(context.Background()).Create(&Event{Kind: "signup"})
+}
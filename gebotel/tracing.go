@@ -0,0 +1,57 @@
+// Package gebotel traces geb queries with OpenTelemetry. It lives in its
+// own package so that importing geb does not pull in the OpenTelemetry SDK
+// and the GORM tracing plugin.
+package gebotel
+
+import (
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/tracing"
+
+	"github.com/cans-communication/geb"
+)
+
+// NewPlugin returns a GORM plugin that records a span for every statement,
+// carrying the query text and its duration. Spans carry the db.system,
+// db.name, net.peer.name and net.peer.port attributes of the database. A
+// nil tp uses the global TracerProvider.
+//
+// host and port are the database as seen by the application; for an SSH
+// connection pass DBHost and DBPort, the address behind the tunnel. Pool
+// metrics are left to gebprom.
+func NewPlugin(tp trace.TracerProvider, dbName, host string, port int) gorm.Plugin {
+	opts := []tracing.Option{
+		tracing.WithoutMetrics(),
+		tracing.WithoutServerAddress(),
+		tracing.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.name", dbName),
+			attribute.String("net.peer.name", host),
+			attribute.String("net.peer.port", strconv.Itoa(port)),
+		),
+	}
+	if tp != nil {
+		opts = append(opts, tracing.WithTracerProvider(tp))
+	}
+	return tracing.NewPlugin(opts...)
+}
+
+// Plugin returns NewPlugin for the database conf connects to, for
+// ConnectConfig.Plugins. The attributes come from DBName, DBHost and
+// DBPort, or from the first entry of Hosts.
+func Plugin(conf geb.ConnectConfig, tp trace.TracerProvider) gorm.Plugin {
+	host, port := conf.DBHost, conf.DBPort
+	if len(conf.Hosts) > 0 {
+		host, port = conf.Hosts[0].Host, conf.Hosts[0].Port
+	}
+	return NewPlugin(tp, conf.DBName, host, port)
+}
+
+// PluginViaSSH is Plugin for ConnectViaSSHConfig.Plugins. The attributes
+// name the database behind the tunnel.
+func PluginViaSSH(conf geb.ConnectViaSSHConfig, tp trace.TracerProvider) gorm.Plugin {
+	return NewPlugin(tp, conf.DBName, conf.DBHost, conf.DBPort)
+}
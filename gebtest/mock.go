@@ -0,0 +1,27 @@
+// Package gebtest provides a *geb.PG whose queries are answered by
+// go-sqlmock, so code that takes a geb client can be unit-tested by
+// declaring the SQL it is expected to run and the rows to return.
+package gebtest
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/cans-communication/geb"
+)
+
+// NewPGWithMock returns a *geb.PG backed by go-sqlmock, with the mock to
+// set expectations on. No ping is sent on open, so none needs to be
+// expected. For sqlmock options, call sqlmock.New and geb.NewPGFromSQLDB
+// directly.
+func NewPGWithMock() (*geb.PG, sqlmock.Sqlmock, error) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	pg, err := geb.NewPGFromSQLDB(sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		return nil, nil, err
+	}
+	return pg, mock, nil
+}
@@ -0,0 +1,72 @@
+package geb
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newLogger(conf ConnectConfig) logger.Interface {
+	if conf.Logger != nil {
+		return conf.Logger
+	}
+	// GORM's levels start at Silent = 1, so zero means unset.
+	if conf.LogLevel != 0 {
+		return logger.Default.LogMode(conf.LogLevel)
+	}
+
+	logMode := logger.Silent
+	if conf.EnableLogDebug {
+		logMode = logger.Info
+	}
+	return logger.Default.LogMode(logMode)
+}
+
+func gormConfig(conf ConnectConfig) *gorm.Config {
+	return &gorm.Config{
+		Logger:                                   newLogger(conf),
+		DisableAutomaticPing:                     true,
+		PrepareStmt:                              conf.PrepareStmt,
+		NamingStrategy:                           conf.NamingStrategy,
+		DisableForeignKeyConstraintWhenMigrating: conf.DisableFKConstraintOnMigrate,
+		SkipDefaultTransaction:                   conf.SkipDefaultTransaction,
+	}
+}
+
+// pgBouncerPort is PgBouncer's default listen port.
+const pgBouncerPort = 6432
+
+// warnPrepareStmt flags PrepareStmt on what looks like a PgBouncer port:
+// in transaction pooling mode the cached statements end up on server
+// connections other than the one they were prepared on.
+func warnPrepareStmt(ctx context.Context, db *gorm.DB, conf ConnectConfig) {
+	if !conf.PrepareStmt {
+		return
+	}
+	ports := []int{conf.DBPort}
+	for _, hp := range conf.Hosts {
+		ports = append(ports, hp.Port)
+	}
+	if slices.Contains(ports, pgBouncerPort) {
+		db.Logger.Warn(ctx, "PrepareStmt is enabled on port %d, PgBouncer's default; prepared statements fail behind PgBouncer in transaction pooling mode", pgBouncerPort)
+	}
+}
+
+func usePlugins(db *gorm.DB, conf ConnectConfig) error {
+	if err := keepPinnedConn(db); err != nil {
+		return err
+	}
+	plugins := conf.Plugins
+	if conf.DefaultQueryTimeout > 0 {
+		plugins = append([]gorm.Plugin{queryTimeout{conf.DefaultQueryTimeout}}, plugins...)
+	}
+	for _, plugin := range plugins {
+		if err := db.Use(plugin); err != nil {
+			return fmt.Errorf("register GORM plugin %s: %w", plugin.Name(), err)
+		}
+	}
+	return honorPrimary(db)
+}
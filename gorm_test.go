@@ -0,0 +1,123 @@
+package geb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// nopConnector opens connections that accept every statement without a
+// server, so benchmarks measure the client side alone.
+type nopConnector struct{}
+
+func (nopConnector) Connect(context.Context) (driver.Conn, error) { return nopConn{}, nil }
+func (nopConnector) Driver() driver.Driver                        { return nil }
+
+type nopConn struct{}
+
+func (nopConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (nopConn) Close() error                        { return nil }
+func (nopConn) Begin() (driver.Tx, error)           { return nopConn{}, nil }
+func (nopConn) Commit() error                       { return nil }
+func (nopConn) Rollback() error                     { return nil }
+
+func (nopConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+// QueryContext returns a single row with id 1, which is what an
+// INSERT ... RETURNING "id" reads back.
+func (nopConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &nopRows{}, nil
+}
+
+type nopRows struct{ done bool }
+
+func (*nopRows) Columns() []string { return []string{"id"} }
+func (*nopRows) Close() error      { return nil }
+func (r *nopRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+type benchEvent struct {
+	ID      int64
+	Kind    string
+	Payload string
+}
+
+func BenchmarkCreate(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		skip bool
+	}{
+		{"DefaultTransaction", false},
+		{"SkipDefaultTransaction", true},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			sqlDB := sql.OpenDB(nopConnector{})
+			defer sqlDB.Close()
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), gormConfig(ConnectConfig{SkipDefaultTransaction: bench.skip}))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := db.Create(&benchEvent{Kind: "click", Payload: "{}"}).Error; err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// failingPlugin fails to register and keeps the *gorm.DB it was given.
+type failingPlugin struct{ db **gorm.DB }
+
+func (failingPlugin) Name() string { return "geb:test:failing" }
+
+func (p failingPlugin) Initialize(db *gorm.DB) error {
+	*p.db = db
+	return errors.New("no can do")
+}
+
+func TestConnectClosesPoolWhenPluginFails(t *testing.T) {
+	for _, driverName := range []string{DriverPGX, DriverPQ} {
+		t.Run(driverName, func(t *testing.T) {
+			var db *gorm.DB
+			pg, err := Connect(ConnectConfig{
+				DBHost:  "127.0.0.1",
+				DBPort:  closedPort(t),
+				DBUser:  "app",
+				DBName:  "orders",
+				SSLMode: "disable",
+				Driver:  driverName,
+				Plugins: []gorm.Plugin{failingPlugin{&db}},
+			})
+			if pg != nil || err == nil || !strings.Contains(err.Error(), "register GORM plugin geb:test:failing: no can do") {
+				t.Fatalf("Connect = %v, %v; want the plugin error", pg, err)
+			}
+			if db == nil {
+				t.Fatal("plugin was not initialized")
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := sqlDB.Ping(); err == nil || !strings.Contains(err.Error(), "database is closed") {
+				t.Errorf("Ping after the failed Connect = %v, want the pool closed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package geb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+const healthCheckTimeout = 5 * time.Second
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthzResponse struct {
+	Status string                    `json:"status"`
+	Checks map[string]healthResponse `json:"checks"`
+}
+
+// HealthHandler serves a readiness probe: 200 {"status":"ok"} when Ping
+// succeeds within 5 seconds, 503 with the error otherwise.
+func (pg *PG) HealthHandler() http.HandlerFunc {
+	return healthHandler(pg)
+}
+
+// HealthHandler serves a readiness probe: 200 {"status":"ok"} when Ping
+// succeeds within 5 seconds, 503 with the error otherwise.
+func (pg *PGViaSSH) HealthHandler() http.HandlerFunc {
+	return healthHandler(pg)
+}
+
+// PingTimeout pings the database, giving up after d.
+func (pg *PG) PingTimeout(d time.Duration) error {
+	return pingTimeout(pg, d)
+}
+
+// PingTimeout pings the database through the tunnel, giving up after d.
+func (pg *PGViaSSH) PingTimeout(d time.Duration) error {
+	return pingTimeout(pg, d)
+}
+
+func pingTimeout(p Pinger, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return p.Ping(ctx)
+}
+
+func healthHandler(p Pinger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := p.Ping(ctx); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(healthResponse{Status: "unavailable", Error: err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(healthResponse{Status: "ok"})
+	}
+}
+
+// Healthz pings every client concurrently, so one hung database doesn't
+// delay the others' results, and returns each name's Ping error, nil for
+// healthy ones. Bound ctx to bound the whole check.
+func Healthz(ctx context.Context, clients map[string]Client) map[string]error {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]error, len(clients))
+	)
+	for name, client := range clients {
+		wg.Go(func() {
+			err := client.Ping(ctx)
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// HealthzHandler serves Healthz as a readiness probe with a 5 second
+// timeout: 200 when every client is healthy, 503 when any is not, with
+// the status of each by name.
+func HealthzHandler(clients map[string]Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		resp := healthzResponse{Status: "ok", Checks: make(map[string]healthResponse, len(clients))}
+		for name, err := range Healthz(ctx, clients) {
+			if err != nil {
+				resp.Status = "unavailable"
+				resp.Checks[name] = healthResponse{Status: "unavailable", Error: err.Error()}
+				continue
+			}
+			resp.Checks[name] = healthResponse{Status: "ok"}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,43 @@
+package geb
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var ErrNoHostKeyVerification = errors.New("no SSH host key verification configured: set SSHHostKeyCallback, KnownHostsPath, SSHHostKeyFingerprint or InsecureSkipHostKeyVerify")
+
+// hostKeyCallback uses the first verification configured, in the order
+// SSHHostKeyCallback, KnownHostsPath, SSHHostKeyFingerprint,
+// InsecureSkipHostKeyVerify.
+func hostKeyCallback(conf ConnectViaSSHConfig) (ssh.HostKeyCallback, error) {
+	switch {
+	case conf.SSHHostKeyCallback != nil:
+		return conf.SSHHostKeyCallback, nil
+	case conf.KnownHostsPath != "":
+		return knownhosts.New(conf.KnownHostsPath)
+	case conf.SSHHostKeyFingerprint != "":
+		return fingerprintCallback(conf.SSHHostKeyFingerprint), nil
+	case conf.InsecureSkipHostKeyVerify:
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	return nil, ErrNoHostKeyVerification
+}
+
+// fingerprintCallback pins a single SHA256 host key fingerprint, with or
+// without the "SHA256:" prefix printed by ssh-keygen -l.
+func fingerprintCallback(fingerprint string) ssh.HostKeyCallback {
+	want := strings.TrimPrefix(fingerprint, "SHA256:")
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		got := ssh.FingerprintSHA256(key)
+		if strings.TrimPrefix(got, "SHA256:") != want {
+			return fmt.Errorf("ssh: host key for %s has fingerprint %s, want SHA256:%s", hostname, got, want)
+		}
+		return nil
+	}
+}
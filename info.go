@@ -0,0 +1,58 @@
+package geb
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ConnInfo describes the server and session behind a connection, for
+// diagnostics endpoints.
+type ConnInfo struct {
+	ServerVersion string
+	Database      string
+	User          string
+	// Connections counts the server sessions sharing this pool's
+	// application_name, including the one that ran the query.
+	Connections int64
+	// SSHAddress is the SSH server's address, set by PGViaSSH only.
+	SSHAddress string
+}
+
+const connInfoQuery = `SELECT current_setting('server_version'), current_database(), current_user,
+	(SELECT count(*) FROM pg_stat_activity WHERE application_name = current_setting('application_name'))`
+
+// Info reports the server version, the current database and user, and how
+// many sessions share this pool's application name. It runs a single
+// read-only query bounded by ctx.
+func (pg *PG) Info(ctx context.Context) (ConnInfo, error) {
+	return connInfo(ctx, pg.GormDB())
+}
+
+// Info is PG.Info plus the address of the SSH server.
+func (pg *PGViaSSH) Info(ctx context.Context) (ConnInfo, error) {
+	info, err := connInfo(ctx, pg.GormDB())
+
+	if err != nil {
+		return info, err
+	}
+
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
+	// A client borrowed through ConnectViaExistingSSH has no SSHHost.
+	info.SSHAddress = pg.SSHCon.RemoteAddr().String()
+
+	if pg.conf.SSHHost != "" {
+		info.SSHAddress = fmt.Sprintf("%s:%d", pg.conf.SSHHost, pg.conf.SSHPort)
+	}
+
+	return info, nil
+}
+
+func connInfo(ctx context.Context, db *gorm.DB) (ConnInfo, error) {
+	var info ConnInfo
+	err := db.WithContext(ctx).Raw(connInfoQuery).Row().Scan(&info.ServerVersion, &info.Database, &info.User, &info.Connections)
+	return info, err
+}
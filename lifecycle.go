@@ -0,0 +1,55 @@
+package geb
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConnEvent is passed to the OnConnect, OnClose and OnReconnect hooks.
+type ConnEvent struct {
+	// Addr is DBHost:DBPort, or the comma-separated Hosts.
+	Addr     string
+	Database string
+	// SSHAddr is the SSH server's host:port, for tunneled connections
+	// dialed by the package.
+	SSHAddr string
+	// Duration is how long the connect, close or reconnect took.
+	Duration time.Duration
+	// Err is the error returned to the caller, if any.
+	Err error
+}
+
+func (conf ConnectConfig) connEvent(start time.Time, err error) ConnEvent {
+	addr := net.JoinHostPort(conf.DBHost, strconv.Itoa(conf.DBPort))
+	if len(conf.Hosts) > 0 {
+		addrs := make([]string, len(conf.Hosts))
+		for i, hp := range conf.Hosts {
+			addrs[i] = net.JoinHostPort(hp.Host, strconv.Itoa(hp.Port))
+		}
+		addr = strings.Join(addrs, ",")
+	}
+	return ConnEvent{
+		Addr:     addr,
+		Database: conf.DBName,
+		Duration: time.Since(start),
+		Err:      err,
+	}
+}
+
+func (conf ConnectViaSSHConfig) connEvent(start time.Time, err error) ConnEvent {
+	ev := conf.connectConfig().connEvent(start, err)
+	if conf.SSHHost != "" {
+		ev.SSHAddr = fmt.Sprintf("%s:%d", conf.SSHHost, conf.SSHPort)
+	}
+	return ev
+}
+
+// emit calls hook, which may be nil.
+func emit(hook func(ConnEvent), ev ConnEvent) {
+	if hook != nil {
+		hook(ev)
+	}
+}
@@ -0,0 +1,121 @@
+package geb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+const (
+	listenMinBackoff = 500 * time.Millisecond
+	listenMaxBackoff = 30 * time.Second
+)
+
+var errListenDriver = errors.New("Listen requires the pgx driver")
+
+// Notification is a message received on a channel passed to Listen.
+type Notification struct {
+	Channel string
+	Payload string
+	PID     uint32
+}
+
+// Listen subscribes to channel with LISTEN and delivers its notifications
+// until ctx is done, when the returned channel is closed. It holds one
+// connection of the pool for as long as it runs. If that connection is
+// lost, Listen takes a new one from the pool and LISTENs again, backing
+// off between attempts; notifications sent in the meantime are missed.
+// Only the initial LISTEN reports its error.
+func (pg *PG) Listen(ctx context.Context, channel string) (<-chan Notification, error) {
+	conn, err := pg.listenConn(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make(chan Notification)
+	go func() {
+		defer close(notifications)
+		backoff := listenMinBackoff
+		for {
+			if conn == nil {
+				timer := time.NewTimer(backoff)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+				backoff = min(backoff*2, listenMaxBackoff)
+				if conn, err = pg.listenConn(ctx, channel); err != nil {
+					pg.GormDB().Logger.Warn(ctx, "LISTEN %s: %v", channel, err)
+					continue
+				}
+				backoff = listenMinBackoff
+			}
+
+			n, err := waitForNotification(ctx, conn)
+			if err != nil {
+				discardConn(conn)
+				conn = nil
+				if ctx.Err() != nil {
+					return
+				}
+				pg.GormDB().Logger.Warn(ctx, "LISTEN %s lost its connection, reconnecting: %v", channel, err)
+				continue
+			}
+			select {
+			case notifications <- Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID}:
+			case <-ctx.Done():
+				discardConn(conn)
+				return
+			}
+		}
+	}()
+	return notifications, nil
+}
+
+// listenConn pins a pool connection and runs LISTEN channel on it.
+func (pg *PG) listenConn(ctx context.Context, channel string) (*sql.Conn, error) {
+	sqlDB, err := pg.GormDB().DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errListenDriver
+		}
+		_, err := c.Conn().Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+		return err
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func waitForNotification(ctx context.Context, conn *sql.Conn) (n *pgconn.Notification, err error) {
+	err = conn.Raw(func(driverConn any) error {
+		n, err = driverConn.(*stdlib.Conn).Conn().WaitForNotification(ctx)
+		return err
+	})
+	return n, err
+}
+
+// discardConn closes the session instead of returning it to the pool still
+// subscribed.
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
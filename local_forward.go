@@ -0,0 +1,85 @@
+package geb
+
+import (
+	"io"
+	"net"
+	"strconv"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// LocalAddr returns the address of the listener opened for
+// LocalForwardAddr, e.g. "127.0.0.1:54321", or "" if there is none.
+func (pg *PGViaSSH) LocalAddr() string {
+	pg.mu.RLock()
+	defer pg.mu.RUnlock()
+
+	if pg.forward == nil {
+		return ""
+	}
+
+	return pg.forward.Addr().String()
+}
+
+// listenLocalForward opens a local listener whose connections are forwarded
+// to DBHost:DBPort through the SSH client, for tools such as psql. Each
+// connection dials through the client current at accept time, so the
+// listener survives Reconnect.
+func (pg *PGViaSSH) listenLocalForward(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return err
+	}
+
+	pg.mu.Lock()
+	pg.forward = ln
+	target := net.JoinHostPort(pg.conf.DBHost, strconv.Itoa(pg.conf.DBPort))
+	pg.mu.Unlock()
+
+	go func() {
+		for {
+			local, err := ln.Accept()
+
+			if err != nil {
+				return
+			}
+
+			pg.mu.RLock()
+			client := pg.SSHCon
+			pg.mu.RUnlock()
+
+			go forwardConn(local, client, target)
+		}
+	}()
+
+	return nil
+}
+
+func forwardConn(local net.Conn, client *ssh.Client, target string) {
+	defer local.Close()
+
+	remote, err := client.Dial("tcp", target)
+
+	if err != nil {
+		return
+	}
+
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+
+	// Either side hanging up ends the forward; the deferred closes unblock
+	// the other copy.
+	<-done
+}
@@ -0,0 +1,72 @@
+package geb
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// defaultMigrationLockKey is "geb" in ASCII.
+const defaultMigrationLockKey int64 = 0x676562
+
+// Migrate runs AutoMigrate while holding an advisory lock, so instances
+// starting together during a rolling deploy migrate one at a time. The lock
+// key is ConnectConfig.MigrationLockKey.
+func (pg *PG) Migrate(ctx context.Context, models ...interface{}) error {
+	return withAdvisoryLock(ctx, pg.GormDB(), pg.migrationLockKey(), func(tx *gorm.DB) error {
+		return tx.AutoMigrate(models...)
+	})
+}
+
+func (pg *PG) migrationLockKey() int64 {
+	if pg.conf.MigrationLockKey != 0 {
+		return pg.conf.MigrationLockKey
+	}
+	return defaultMigrationLockKey
+}
+
+// PendingMigrations reports what Migrate would add for models, one line per
+// missing table, column or index, e.g. `add column "users"."email"`. It
+// only reads the catalog; changes to existing columns, such as a new type
+// or default, are not detected.
+func (pg *PG) PendingMigrations(ctx context.Context, models ...interface{}) ([]string, error) {
+	// The Migrator's Has* checks report false on query errors, which would
+	// read as everything missing, so make sure the database answers first.
+	if err := pg.Ping(ctx); err != nil {
+		return nil, err
+	}
+	db := pg.GormDB().WithContext(ctx)
+	migrator := db.Migrator()
+
+	var pending []string
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		table := stmt.Schema.Table
+
+		if !migrator.HasTable(model) {
+			pending = append(pending, fmt.Sprintf("create table %q", table))
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			if !migrator.HasColumn(model, field.DBName) {
+				pending = append(pending, fmt.Sprintf("add column %q.%q", table, field.DBName))
+			}
+		}
+		indexes := stmt.Schema.ParseIndexes()
+		for _, name := range slices.Sorted(maps.Keys(indexes)) {
+			if !migrator.HasIndex(model, name) {
+				pending = append(pending, fmt.Sprintf("create index %q on %q", name, table))
+			}
+		}
+	}
+	return pending, ctx.Err()
+}
@@ -0,0 +1,81 @@
+package geb
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+
+	sqlStateClassConnectionException = "08"
+	sqlStateAdminShutdown            = "57P01"
+	sqlStateCrashShutdown            = "57P02"
+	sqlStateCannotConnectNow         = "57P03"
+
+	sqlStateTooManyConnections   = "53300"
+	sqlStateInvalidAuthorization = "28000"
+	sqlStateInvalidPassword      = "28P01"
+)
+
+// pgErrorCode returns the SQLSTATE of a pgx or lib/pq error, or "" if err
+// did not come from the server.
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
+
+// isConnError reports whether err means the connection itself broke, as
+// opposed to the server rejecting a statement.
+func isConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if isRetryableConnError(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isRetryableConnError reports connection errors after which the failed
+// statement cannot have taken effect: pgx knows nothing was sent, or the
+// server ended the session, which rolls back whatever it was running. A
+// connection dropped mid-statement is not one of them, since a write may
+// have committed before the reply was lost.
+func isRetryableConnError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || pgconn.SafeToRetry(err) {
+		return true
+	}
+	switch code := pgErrorCode(err); {
+	case strings.HasPrefix(code, sqlStateClassConnectionException):
+		return true
+	case code == sqlStateAdminShutdown, code == sqlStateCrashShutdown, code == sqlStateCannotConnectNow:
+		return true
+	}
+	return false
+}
+
+// isPermanentConnectError reports connect failures that retrying cannot
+// fix: wrong credentials, or pg_hba.conf rejecting the login.
+func isPermanentConnectError(err error) bool {
+	switch pgErrorCode(err) {
+	case sqlStateInvalidPassword, sqlStateInvalidAuthorization:
+		return true
+	}
+	return false
+}
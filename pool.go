@@ -0,0 +1,96 @@
+package geb
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func configurePool(ctx context.Context, db *gorm.DB, sqlDB *sql.DB, conf ConnectConfig) {
+	maxIdle := conf.MaxIdleCon
+	if conf.MaxOpenConns > 0 && maxIdle > conf.MaxOpenConns {
+		db.Logger.Warn(ctx, "MaxIdleCon (%d) is greater than MaxOpenConns (%d), clamping to %d",
+			maxIdle, conf.MaxOpenConns, conf.MaxOpenConns)
+		maxIdle = conf.MaxOpenConns
+	}
+
+	sqlDB.SetMaxIdleConns(maxIdle)
+	if conf.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
+	}
+	sqlDB.SetConnMaxLifetime(conf.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
+}
+
+// autoTunePoolShare is the part of the server's connections that all
+// instances of a service get together under AutoTunePool, leaving the rest
+// to other clients, admin sessions and deploys overlapping old instances.
+const autoTunePoolShare = 0.5
+
+const autoTunePoolQuery = `SELECT current_setting('max_connections')::int,
+	current_setting('superuser_reserved_connections')::int,
+	COALESCE(current_setting('reserved_connections', true), '0')::int`
+
+// autoTunePool sizes the pool from the server's connection limits when
+// AutoTunePool is set and no size was given. It runs after the first ping;
+// if the settings can't be read, the pool is left as configured.
+func autoTunePool(ctx context.Context, db *gorm.DB, sqlDB *sql.DB, conf ConnectConfig) {
+	if !conf.AutoTunePool || conf.MaxOpenConns > 0 || conf.MaxIdleCon > 0 {
+		return
+	}
+
+	var maxConns, superuserReserved, reserved int
+	err := sqlDB.QueryRowContext(ctx, autoTunePoolQuery).Scan(&maxConns, &superuserReserved, &reserved)
+	if err != nil {
+		db.Logger.Warn(ctx, "AutoTunePool: reading max_connections failed, pool left unsized: %v", err)
+		return
+	}
+
+	maxOpen, maxIdle := autoPoolSize(maxConns-superuserReserved-reserved, conf.InstanceCount)
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	db.Logger.Info(ctx, "AutoTunePool: max_connections=%d, reserved=%d, instances=%d: MaxOpenConns=%d, MaxIdleCon=%d",
+		maxConns, superuserReserved+reserved, max(conf.InstanceCount, 1), maxOpen, maxIdle)
+}
+
+// autoPoolSize splits autoTunePoolShare of the available connections
+// between the instances, keeping a quarter of each pool idle.
+func autoPoolSize(available, instances int) (maxOpen, maxIdle int) {
+	maxOpen = int(float64(available)*autoTunePoolShare) / max(instances, 1)
+	maxOpen = max(maxOpen, 2)
+	return maxOpen, max(maxOpen/4, 1)
+}
+
+// jitterConnMaxLifetime re-rolls the pool's ConnMaxLifetime within
+// [ConnMaxLifetime, ConnMaxLifetime+ConnMaxLifetimeJitter] until ctx is
+// done. database/sql has no per-connection lifetime, so connections opened
+// together still expire together; what the jitter breaks up is the lockstep
+// between successive generations of connections and between processes
+// sharing the same config.
+func jitterConnMaxLifetime(ctx context.Context, sqlDB *sql.DB, conf ConnectConfig) {
+	if conf.ConnMaxLifetime <= 0 || conf.ConnMaxLifetimeJitter <= 0 {
+		return
+	}
+
+	roll := func() {
+		sqlDB.SetConnMaxLifetime(conf.ConnMaxLifetime + rand.N(conf.ConnMaxLifetimeJitter+1))
+	}
+	roll()
+
+	go func() {
+		ticker := time.NewTicker(max(conf.ConnMaxLifetimeJitter/2, time.Second))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				roll()
+			}
+		}
+	}()
+}
@@ -0,0 +1,94 @@
+package geb
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNoPGStatStatements = errors.New("pg_stat_statements is not installed: add it to shared_preload_libraries and run CREATE EXTENSION pg_stat_statements")
+
+// QueryStat is one normalized statement from pg_stat_statements.
+type QueryStat struct {
+	Query     string
+	Calls     int64
+	TotalTime time.Duration
+	MeanTime  time.Duration
+}
+
+// PostgreSQL 13 renamed total_time and mean_time to total_exec_time and
+// mean_exec_time.
+const (
+	topQueriesQuery = `SELECT query, calls, total_exec_time, mean_exec_time FROM pg_stat_statements
+	ORDER BY total_exec_time DESC LIMIT ?`
+	topQueriesQuery12 = `SELECT query, calls, total_time, mean_time FROM pg_stat_statements
+	ORDER BY total_time DESC LIMIT ?`
+)
+
+// TopQueries returns the limit statements with the highest total execution
+// time, as recorded by pg_stat_statements since its last reset.
+func (pg *PG) TopQueries(ctx context.Context, limit int) ([]QueryStat, error) {
+	db := pg.GormDB().WithContext(ctx)
+	if err := checkPGStatStatements(ctx, pg); err != nil {
+		return nil, err
+	}
+
+	var version int
+	if err := db.Raw("SELECT current_setting('server_version_num')::int").Scan(&version).Error; err != nil {
+		return nil, err
+	}
+	query := topQueriesQuery
+	if version < 130000 {
+		query = topQueriesQuery12
+	}
+
+	rows, err := db.Raw(query, limit).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []QueryStat
+	for rows.Next() {
+		var (
+			stat            QueryStat
+			totalMs, meanMs float64
+		)
+		if err := rows.Scan(&stat.Query, &stat.Calls, &totalMs, &meanMs); err != nil {
+			return nil, err
+		}
+		stat.TotalTime = msDuration(totalMs)
+		stat.MeanTime = msDuration(meanMs)
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// ResetQueryStats discards everything pg_stat_statements has recorded, e.g.
+// before measuring a load test. It needs superuser or a role granted
+// EXECUTE on pg_stat_statements_reset.
+func (pg *PG) ResetQueryStats(ctx context.Context) error {
+	if err := checkPGStatStatements(ctx, pg); err != nil {
+		return err
+	}
+	return pg.GormDB().WithContext(ctx).Exec("SELECT pg_stat_statements_reset()").Error
+}
+
+func checkPGStatStatements(ctx context.Context, pg *PG) error {
+	var installed bool
+	err := pg.GormDB().WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')").
+		Scan(&installed).Error
+	if err != nil {
+		return err
+	}
+	if !installed {
+		return ErrNoPGStatStatements
+	}
+	return nil
+}
+
+// msDuration converts the milliseconds pg_stat_statements reports.
+func msDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,76 @@
+package geb
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryTimeoutSetting = "geb:query_timeout"
+
+// queryTimeout bounds every statement whose context has no deadline.
+// Row and Rows are left alone, as their rows are read after the callbacks
+// have returned and cancelling would cut the read short.
+type queryTimeout struct {
+	timeout time.Duration
+}
+
+type queryTimeoutState struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+func (p queryTimeout) Name() string {
+	return queryTimeoutSetting
+}
+
+func (p queryTimeout) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name     string
+		callback interface {
+			Register(name string, fn func(*gorm.DB)) error
+		}
+		fn func(*gorm.DB)
+	}{
+		{"create:before", cb.Create().Before("*"), p.before},
+		{"create:after", cb.Create().After("*"), p.after},
+		{"query:before", cb.Query().Before("*"), p.before},
+		{"query:after", cb.Query().After("*"), p.after},
+		{"update:before", cb.Update().Before("*"), p.before},
+		{"update:after", cb.Update().After("*"), p.after},
+		{"delete:before", cb.Delete().Before("*"), p.before},
+		{"delete:after", cb.Delete().After("*"), p.after},
+		{"raw:before", cb.Raw().Before("*"), p.before},
+		{"raw:after", cb.Raw().After("*"), p.after},
+	}
+	for _, h := range hooks {
+		if err := h.callback.Register(queryTimeoutSetting+":"+h.name, h.fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p queryTimeout) before(tx *gorm.DB) {
+	parent := tx.Statement.Context
+	if _, ok := parent.Deadline(); ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(parent, p.timeout)
+	tx.Statement.Context = ctx
+	tx.Statement.Settings.Store(queryTimeoutSetting, queryTimeoutState{parent: parent, cancel: cancel})
+}
+
+// after releases the timeout and restores the caller's context, so a
+// Statement reused for a second operation doesn't inherit the expired one.
+func (p queryTimeout) after(tx *gorm.DB) {
+	v, ok := tx.Statement.Settings.LoadAndDelete(queryTimeoutSetting)
+	if !ok {
+		return
+	}
+	state := v.(queryTimeoutState)
+	state.cancel()
+	tx.Statement.Context = state.parent
+}
@@ -0,0 +1,240 @@
+package geb
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaConfig fills the connection settings a replica leaves empty from
+// the primary.
+func (conf ConnectConfig) replicaConfig(replica ConnectConfig) ConnectConfig {
+	inherit := func(field *string, primary string) {
+		if *field == "" {
+			*field = primary
+		}
+	}
+	if replica.DBPort == 0 {
+		replica.DBPort = conf.DBPort
+	}
+	inherit(&replica.DBUser, conf.DBUser)
+	// Tokens from a PasswordProvider are usually bound to one host, so the
+	// primary's is not inherited.
+	if replica.PasswordProvider == nil {
+		inherit(&replica.DBPassword, conf.DBPassword)
+	}
+	inherit(&replica.DBName, conf.DBName)
+	inherit(&replica.SSLMode, conf.SSLMode)
+	inherit(&replica.ChannelBinding, conf.ChannelBinding)
+	if replica.SSLRootCert == "" && replica.SSLRootCertPEM == "" {
+		replica.SSLRootCert, replica.SSLRootCertPEM = conf.SSLRootCert, conf.SSLRootCertPEM
+	}
+	if replica.SSLCert == "" && replica.SSLCertPEM == "" {
+		replica.SSLCert, replica.SSLCertPEM = conf.SSLCert, conf.SSLCertPEM
+	}
+	if replica.SSLKey == "" && replica.SSLKeyPEM == "" {
+		replica.SSLKey, replica.SSLKeyPEM = conf.SSLKey, conf.SSLKeyPEM
+	}
+	inherit(&replica.AppName, conf.AppName)
+	if replica.TimeZone == "" && !replica.SkipTimeZone {
+		replica.TimeZone, replica.SkipTimeZone = conf.TimeZone, conf.SkipTimeZone
+	}
+	inherit(&replica.Driver, conf.Driver)
+	inherit(&replica.SearchPath, conf.SearchPath)
+	if replica.RuntimeParams == nil {
+		replica.RuntimeParams = conf.RuntimeParams
+	}
+	inherit(&replica.RawParams, conf.RawParams)
+	replica.ReadOnly = replica.ReadOnly || conf.ReadOnly
+	if replica.ConnectTimeout == 0 {
+		replica.ConnectTimeout = conf.ConnectTimeout
+	}
+	if replica.MaxIdleCon == 0 && replica.MaxOpenConns == 0 {
+		replica.MaxIdleCon, replica.MaxOpenConns = conf.MaxIdleCon, conf.MaxOpenConns
+		replica.AutoTunePool = replica.AutoTunePool || conf.AutoTunePool
+	}
+	if replica.InstanceCount == 0 {
+		replica.InstanceCount = conf.InstanceCount
+	}
+	if replica.ConnMaxLifetime == 0 {
+		replica.ConnMaxLifetime = conf.ConnMaxLifetime
+	}
+	if replica.ConnMaxIdleTime == 0 {
+		replica.ConnMaxIdleTime = conf.ConnMaxIdleTime
+	}
+	if replica.ConnMaxLifetimeJitter == 0 {
+		replica.ConnMaxLifetimeJitter = conf.ConnMaxLifetimeJitter
+	}
+	if replica.StatementTimeout == 0 {
+		replica.StatementTimeout = conf.StatementTimeout
+	}
+	if replica.IdleInTxTimeout == 0 {
+		replica.IdleInTxTimeout = conf.IdleInTxTimeout
+	}
+	if replica.DialFunc == nil && replica.SOCKS5Proxy == "" {
+		replica.DialFunc = conf.DialFunc
+		replica.SOCKS5Proxy, replica.SOCKS5User, replica.SOCKS5Password = conf.SOCKS5Proxy, conf.SOCKS5User, conf.SOCKS5Password
+	}
+	replica.ReadReplicas = nil
+	return replica
+}
+
+// useReplicas opens a pool per replica and registers dbresolver so reads
+// go to the replicas and writes to the primary. The caller is responsible
+// for closing the returned pools and removing the temp files.
+func useReplicas(ctx context.Context, db *gorm.DB, conf ConnectConfig) ([]*sql.DB, []string, error) {
+	var (
+		pools      []*sql.DB
+		tempFiles  []string
+		dialectors []gorm.Dialector
+	)
+	cleanup := func() {
+		for _, pool := range pools {
+			pool.Close()
+		}
+		removeFiles(tempFiles)
+	}
+
+	for _, replica := range conf.ReadReplicas {
+		replica = conf.replicaConfig(replica)
+		files, err := writeSSLFiles(&replica)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		tempFiles = append(tempFiles, files...)
+
+		dsn := BuildDSN(replica)
+		pool, err := openSQLDB(replica, dsn)
+		if err != nil {
+			cleanup()
+			return nil, nil, redactError(connectError(ErrDBOpen, err), dsn, replica.DBPassword)
+		}
+		pools = append(pools, pool)
+		configurePool(ctx, db, pool, replica)
+
+		if err := pool.PingContext(ctx); err != nil {
+			cleanup()
+			return nil, nil, redactError(connectError(ErrDBPing, err), dsn, replica.DBPassword)
+		}
+		autoTunePool(ctx, db, pool, replica)
+		dialectors = append(dialectors, postgres.New(postgres.Config{
+			Conn: pool,
+		}))
+	}
+
+	err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   dbresolver.RandomPolicy{},
+	}))
+	if err == nil {
+		err = honorPrimary(db)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return pools, tempFiles, nil
+}
+
+// UsePrimary returns a handle whose queries always go to the primary, for
+// reads that must see a write that may not have replicated yet.
+func (pg *PG) UsePrimary(ctx context.Context) *gorm.DB {
+	return pg.GormDB().WithContext(ctx).Clauses(dbresolver.Write)
+}
+
+type primaryKey struct{}
+
+// Primary marks ctx so that queries run with it go to the primary instead
+// of a replica, e.g. for the rest of a request that just wrote.
+func Primary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func isPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+const (
+	dbresolverName      = "gorm:db_resolver"
+	primaryCallbackName = "geb:primary"
+)
+
+// honorPrimary makes a registered dbresolver route reads to the primary
+// for contexts marked with Primary. It is a no-op without dbresolver.
+// GORM rejects Before(dbresolverName), as dbresolver itself is registered
+// Before("*"); among those, the callback registered last runs first, so
+// this has to be called after dbresolver is.
+func honorPrimary(db *gorm.DB) error {
+	if _, ok := db.Plugins[dbresolverName]; !ok {
+		return nil
+	}
+	if db.Callback().Query().Get(primaryCallbackName) != nil {
+		return nil
+	}
+	usePrimary := func(db *gorm.DB) {
+		if db.Statement.Context != nil && isPrimary(db.Statement.Context) {
+			dbresolver.Write.ModifyStatement(db.Statement)
+		}
+	}
+	cb := db.Callback()
+	for _, callback := range []interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{
+		cb.Query().Before("*"),
+		cb.Row().Before("*"),
+		cb.Raw().Before("*"),
+	} {
+		if err := callback.Register(primaryCallbackName, usePrimary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const pinnedConnSetting = "geb:pinned_conn"
+
+// pinConn makes the statements run through tx use conn, a connection
+// taken out of the pool. Transactions begun on tx run on conn as well.
+func pinConn(tx *gorm.DB, conn *sql.Conn) *gorm.DB {
+	tx.Statement.ConnPool = conn
+	tx.Statement.Settings.Store(pinnedConnSetting, conn)
+	return tx
+}
+
+// keepPinnedConn undoes dbresolver for statements run on a pinned
+// connection: it only leaves transactions alone, so it would send them to
+// a pool, and reads to a replica. Callbacks registered Before("*") run in
+// reverse order, so this has to be registered before dbresolver is to run
+// after it.
+func keepPinnedConn(db *gorm.DB) error {
+	repin := func(db *gorm.DB) {
+		conn, ok := db.Statement.Settings.Load(pinnedConnSetting)
+		if !ok {
+			return
+		}
+		if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); !inTx {
+			db.Statement.ConnPool = conn.(*sql.Conn)
+		}
+	}
+	cb := db.Callback()
+	for _, callback := range []interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{
+		cb.Create().Before("*"),
+		cb.Query().Before("*"),
+		cb.Update().Before("*"),
+		cb.Delete().Before("*"),
+		cb.Row().Before("*"),
+		cb.Raw().Before("*"),
+	} {
+		if err := callback.Register(pinnedConnSetting, repin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
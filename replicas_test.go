@@ -0,0 +1,129 @@
+package geb
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// newPGWithReplica returns a PG whose reads go to a replica, registered
+// the way Connect does, with a mock for the primary and one for the
+// replica. The primary pool holds a single connection, so a statement sent
+// to the pool while a connection is pinned blocks until ctx expires.
+func newPGWithReplica(t *testing.T) (*PG, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	t.Helper()
+	primaryDB, primary, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { primaryDB.Close() })
+	primaryDB.SetMaxOpenConns(1)
+
+	replicaDB, replica, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { replicaDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: primaryDB}), gormConfig(ConnectConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := usePlugins(db, ConnectConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{postgres.New(postgres.Config{Conn: replicaDB})},
+	}))
+	if err == nil {
+		err = honorPrimary(db)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPG(db), primary, replica
+}
+
+func checkMocks(t *testing.T, mocks ...sqlmock.Sqlmock) {
+	t.Helper()
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestDoUsesPinnedConnWithReplicas(t *testing.T) {
+	pg, primary, replica := newPGWithReplica(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	primary.ExpectQuery(regexp.QuoteMeta("SELECT 1")).WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+	primary.ExpectExec(regexp.QuoteMeta("UPDATE accounts SET balance = 0")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := pg.Do(ctx, func(db *gorm.DB) error {
+		var n int
+		if err := db.Raw("SELECT 1").Scan(&n).Error; err != nil {
+			return err
+		}
+		return db.Exec("UPDATE accounts SET balance = 0").Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMocks(t, primary, replica)
+}
+
+func TestApplyMigrationsReadsPrimaryWithReplicas(t *testing.T) {
+	pg, primary, replica := newPGWithReplica(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	fsys := fstest.MapFS{
+		"migrations/001_init.sql":      {Data: []byte("CREATE TABLE accounts (id bigint)")},
+		"migrations/001_init.down.sql": {Data: []byte("DROP TABLE accounts")},
+		"migrations/002_balance.sql":   {Data: []byte("ALTER TABLE accounts ADD balance bigint")},
+	}
+
+	primary.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).WithArgs(defaultMigrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	primary.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).WillReturnResult(sqlmock.NewResult(0, 0))
+	primary.ExpectQuery(regexp.QuoteMeta("SELECT version FROM schema_migrations ORDER BY version")).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("001_init"))
+	primary.ExpectBegin()
+	primary.ExpectExec(regexp.QuoteMeta("ALTER TABLE accounts ADD balance bigint")).WillReturnResult(sqlmock.NewResult(0, 0))
+	primary.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version) VALUES ($1)")).WithArgs("002_balance").WillReturnResult(sqlmock.NewResult(0, 1))
+	primary.ExpectCommit()
+	primary.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(defaultMigrationLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := pg.ApplyMigrations(ctx, fsys, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+	checkMocks(t, primary, replica)
+}
+
+func TestTryAdvisoryLockUsesPinnedConnWithReplicas(t *testing.T) {
+	pg, primary, replica := newPGWithReplica(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	primary.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).WithArgs(int64(42)).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	primary.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "jobs"`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	primary.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).WithArgs(int64(42)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	var count int64
+	acquired, err := lockSession(ctx, pg.GormDB(), 42, true, func(tx *gorm.DB) error {
+		return tx.Table("jobs").Count(&count).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !acquired || count != 3 {
+		t.Fatalf("acquired = %v, count = %d; want true, 3", acquired, count)
+	}
+	checkMocks(t, primary, replica)
+}
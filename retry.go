@@ -0,0 +1,75 @@
+package geb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ConnectWithRetry calls ConnectContext until it succeeds, maxAttempts
+// attempts have been made or ctx is done, waiting backoff before the second
+// attempt and doubling the wait after each failure. It is meant for service
+// startup, when the database may not accept connections yet, or is at
+// max_connections (ErrTooManyClients). An invalid config and a rejected
+// login (SQLSTATE 28P01 or 28000) fail immediately; otherwise the last
+// error is returned.
+func ConnectWithRetry(ctx context.Context, conf ConnectConfig, maxAttempts int, backoff time.Duration) (*PG, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		pg, err := ConnectContext(ctx, conf)
+		if err == nil || attempt >= maxAttempts || ctx.Err() != nil || isPermanentConnectError(err) {
+			return pg, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// WaitForReady pings every interval until the server answers or ctx is
+// done, for an already open pool, e.g. while the database restarts. Unlike
+// ConnectWithRetry it never reopens anything. It returns the last ping
+// error.
+func (pg *PG) WaitForReady(ctx context.Context, interval time.Duration) error {
+	return waitForReady(ctx, pg, interval)
+}
+
+// WaitForReady is PG.WaitForReady, e.g. after Reconnect.
+func (pg *PGViaSSH) WaitForReady(ctx context.Context, interval time.Duration) error {
+	return waitForReady(ctx, pg, interval)
+}
+
+func waitForReady(ctx context.Context, p Pinger, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("wait interval must be positive, got %s", interval)
+	}
+
+	err := p.Ping(ctx)
+	if err == nil {
+		return nil
+	}
+
+	// One timer for the whole wait keeps the loop allocation-free.
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-timer.C:
+		}
+		if err = p.Ping(ctx); err == nil || ctx.Err() != nil {
+			return err
+		}
+		timer.Reset(interval)
+	}
+}
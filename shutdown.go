@@ -0,0 +1,53 @@
+package geb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const shutdownPollInterval = 50 * time.Millisecond
+
+// Shutdown waits for the connections in use to be returned to the pool,
+// then closes it like Close. If ctx is done first, the pool is closed
+// anyway, in the background if that blocks, and the returned error wraps
+// ctx.Err(). Queries started while Shutdown waits are waited for as well,
+// so stop sending work before calling it.
+func (pg *PG) Shutdown(ctx context.Context) error {
+	err := waitIdle(ctx, func() int {
+		inUse := pg.Stats().InUse
+		for _, replica := range pg.replicas {
+			inUse += replica.Stats().InUse
+		}
+		return inUse
+	})
+	return errors.Join(err, pg.Close(ctx))
+}
+
+// Shutdown waits for the connections in use to be returned to the pool,
+// then closes the pool and, only after it, the SSH tunnel. If ctx is done
+// first, both are closed anyway and the returned error wraps ctx.Err().
+func (pg *PGViaSSH) Shutdown(ctx context.Context) error {
+	err := waitIdle(ctx, func() int {
+		return pg.Stats().InUse
+	})
+	return errors.Join(err, pg.Close(ctx))
+}
+
+func waitIdle(ctx context.Context, inUse func() int) error {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		n := inUse()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("shutdown: %d connections still in use: %w", n, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package geb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SlogGormLogger implements logger.Interface by forwarding to a *slog.Logger.
+type SlogGormLogger struct {
+	Logger                    *slog.Logger
+	LogLevel                  logger.LogLevel
+	SlowThreshold             time.Duration
+	IgnoreRecordNotFoundError bool
+}
+
+func NewSlogGormLogger(l *slog.Logger, slow time.Duration) *SlogGormLogger {
+	return &SlogGormLogger{
+		Logger:        l,
+		LogLevel:      logger.Warn,
+		SlowThreshold: slow,
+	}
+}
+
+func (l *SlogGormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	newLogger := *l
+	newLogger.LogLevel = level
+	return &newLogger
+}
+
+func (l *SlogGormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= logger.Info {
+		l.Logger.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *SlogGormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= logger.Warn {
+		l.Logger.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *SlogGormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= logger.Error {
+		l.Logger.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *SlogGormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.LogLevel <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.LogLevel >= logger.Error && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.IgnoreRecordNotFoundError):
+		sql, rows := fc()
+		l.Logger.ErrorContext(ctx, "query failed",
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+			slog.Any("error", err),
+		)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.LogLevel >= logger.Warn:
+		sql, rows := fc()
+		l.Logger.WarnContext(ctx, "slow query",
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+			slog.Duration("threshold", l.SlowThreshold),
+		)
+	case l.LogLevel >= logger.Info:
+		sql, rows := fc()
+		l.Logger.InfoContext(ctx, "query",
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed),
+		)
+	}
+}
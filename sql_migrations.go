@@ -0,0 +1,127 @@
+package geb
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    text PRIMARY KEY,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+// ApplyMigrations runs the pending .sql files of dir in fsys, typically an
+// embed.FS, in lexical order. Each file is one version, named after the file
+// without its extension, and runs in its own transaction together with its
+// row in schema_migrations. Files ending in .down.sql are the rollbacks used
+// by Rollback and are skipped. Like Migrate, it holds the migration advisory
+// lock while it runs.
+func (pg *PG) ApplyMigrations(ctx context.Context, fsys fs.FS, dir string) error {
+	versions, err := migrationVersions(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	return pg.withMigrationLock(ctx, func(tx *gorm.DB) error {
+		applied, err := appliedMigrations(tx)
+		if err != nil {
+			return err
+		}
+		for _, version := range versions {
+			if slices.Contains(applied, version) {
+				continue
+			}
+			query, err := fs.ReadFile(fsys, path.Join(dir, version+".sql"))
+			if err != nil {
+				return err
+			}
+			err = tx.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(string(query)).Error; err != nil {
+					return err
+				}
+				return tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("apply migration %s: %w", version, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Rollback reverts the last steps applied migrations, newest first, by
+// running their .down.sql files from dir in fsys. It fails before touching
+// anything if one of them has no .down.sql file. steps must be at least 1.
+func (pg *PG) Rollback(ctx context.Context, fsys fs.FS, dir string, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("rollback: steps must be at least 1, got %d", steps)
+	}
+	return pg.withMigrationLock(ctx, func(tx *gorm.DB) error {
+		applied, err := appliedMigrations(tx)
+		if err != nil {
+			return err
+		}
+		slices.Reverse(applied)
+		applied = applied[:min(steps, len(applied))]
+
+		queries := make([][]byte, len(applied))
+		for i, version := range applied {
+			queries[i], err = fs.ReadFile(fsys, path.Join(dir, version+".down.sql"))
+			if err != nil {
+				return fmt.Errorf("roll back migration %s: %w", version, err)
+			}
+		}
+
+		for i, version := range applied {
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(string(queries[i])).Error; err != nil {
+					return err
+				}
+				return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("roll back migration %s: %w", version, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (pg *PG) withMigrationLock(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return withAdvisoryLock(ctx, pg.GormDB(), pg.migrationLockKey(), func(tx *gorm.DB) error {
+		if err := tx.Exec(createSchemaMigrations).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// migrationVersions lists the up migrations of dir in lexical order.
+func migrationVersions(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") || strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+		versions = append(versions, strings.TrimSuffix(name, ".sql"))
+	}
+	return versions, nil
+}
+
+// appliedMigrations returns the applied versions in lexical order.
+func appliedMigrations(tx *gorm.DB) ([]string, error) {
+	var versions []string
+	err := tx.Raw("SELECT version FROM schema_migrations ORDER BY version").Scan(&versions).Error
+	return versions, err
+}
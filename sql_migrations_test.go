@@ -0,0 +1,34 @@
+package geb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRollbackRejectsStepsBelowOne(t *testing.T) {
+	sqlDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	pg, err := NewPGFromSQLDB(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"migrations/001_init.sql":      {Data: []byte("CREATE TABLE accounts (id bigint)")},
+		"migrations/001_init.down.sql": {Data: []byte("DROP TABLE accounts")},
+	}
+
+	for _, steps := range []int{0, -1} {
+		// No statement is expected, so taking the lock would fail too.
+		err := pg.Rollback(context.Background(), fsys, "migrations", steps)
+		if err == nil || !strings.Contains(err.Error(), "steps must be at least 1") {
+			t.Errorf("Rollback(%d) = %v, want the steps error", steps, err)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package geb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	sshconfig "github.com/kevinburke/ssh_config"
+	"golang.org/x/crypto/ssh"
+)
+
+var ErrSSHAliasNotFound = errors.New("no Host entry in the SSH config matches the alias")
+
+// defaultIdentityFiles are tried, in order, when the alias sets no
+// IdentityFile, like ssh does.
+var defaultIdentityFiles = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// ConnectViaSSHFromSSHConfig opens the tunnel to a Host alias of
+// ~/.ssh/config, as `ssh alias` would: HostName, Port, User, IdentityFile,
+// CertificateFile, UserKnownHostsFile and ProxyJump are read from the
+// config, ssh-agent is used when SSH_AUTH_SOCK is set, and conf supplies
+// the database side.
+func ConnectViaSSHFromSSHConfig(alias string, conf ConnectConfig) (*PGViaSSH, error) {
+	return ConnectViaSSHFromSSHConfigContext(context.Background(), alias, conf)
+}
+
+func ConnectViaSSHFromSSHConfigContext(ctx context.Context, alias string, conf ConnectConfig) (*PGViaSSH, error) {
+
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return nil, err
+	}
+
+	viaSSH, err := sshConfigFromFile(filepath.Join(home, ".ssh", "config"), alias, conf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ConnectViaSSHContext(ctx, viaSSH)
+}
+
+// sshAlias resolves a Host alias against a parsed SSH config.
+type sshAlias struct {
+	cfg       *sshconfig.Config
+	home      string
+	localUser string
+}
+
+func sshConfigFromFile(path, alias string, conf ConnectConfig) (ConnectViaSSHConfig, error) {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return ConnectViaSSHConfig{}, fmt.Errorf("read SSH config: %w", err)
+	}
+
+	defer f.Close()
+
+	cfg, err := sshconfig.Decode(f)
+
+	if err != nil {
+		return ConnectViaSSHConfig{}, fmt.Errorf("parse SSH config %s: %w", path, err)
+	}
+
+	home, _ := os.UserHomeDir()
+	a := sshAlias{cfg: cfg, home: home, localUser: localUsername()}
+
+	return a.resolve(alias, conf)
+}
+
+func (a sshAlias) resolve(alias string, conf ConnectConfig) (ConnectViaSSHConfig, error) {
+
+	if !a.defines(alias) {
+		return ConnectViaSSHConfig{}, fmt.Errorf("%w: %s", ErrSSHAliasNotFound, alias)
+	}
+
+	if cmd := a.get(alias, "ProxyCommand"); cmd != "" && cmd != "none" {
+		return ConnectViaSSHConfig{}, fmt.Errorf("SSH config for %s: ProxyCommand is not supported, use ProxyJump", alias)
+	}
+
+	viaSSH := conf.viaSSHConfig()
+	host, port, remoteUser, err := a.endpoint(alias)
+
+	if err != nil {
+		return ConnectViaSSHConfig{}, err
+	}
+
+	viaSSH.SSHHost, viaSSH.SSHPort, viaSSH.SSHUser = host, port, remoteUser
+
+	if remoteUser == "" {
+		viaSSH.SSHUser = a.localUser
+	}
+
+	if jump := a.get(alias, "ProxyJump"); jump != "" && jump != "none" {
+		for spec := range strings.SplitSeq(jump, ",") {
+			hop, err := a.hop(strings.TrimSpace(spec))
+
+			if err != nil {
+				return ConnectViaSSHConfig{}, fmt.Errorf("SSH config for %s: ProxyJump: %w", alias, err)
+			}
+
+			viaSSH.Jumps = append(viaSSH.Jumps, hop)
+		}
+	}
+
+	viaSSH.SSHUseAgent = os.Getenv("SSH_AUTH_SOCK") != "" && a.get(alias, "IdentityAgent") != "none"
+
+	key, err := a.identityFile(alias, host, viaSSH.SSHUser, viaSSH.SSHUseAgent)
+
+	if err != nil {
+		return ConnectViaSSHConfig{}, err
+	}
+
+	viaSSH.SSHPrivateKeyPath = key
+
+	if key != "" {
+		viaSSH.SSHCertificate = a.certificateFile(alias, key, host, viaSSH.SSHUser)
+	}
+
+	// Only the first UserKnownHostsFile is used; ssh's own default
+	// otherwise.
+	knownHosts := "~/.ssh/known_hosts"
+
+	if files := strings.Fields(a.get(alias, "UserKnownHostsFile")); len(files) > 0 {
+		knownHosts = files[0]
+	}
+
+	knownHosts = a.expand(knownHosts, host, viaSSH.SSHUser)
+
+	if _, err := os.Stat(knownHosts); err == nil {
+		viaSSH.KnownHostsPath = knownHosts
+	}
+
+	return viaSSH, nil
+}
+
+// defines reports whether a Host entry other than "Host *" matches alias.
+// Entries pulled in through Include only count if they set HostName.
+func (a sshAlias) defines(alias string) bool {
+	for _, host := range a.cfg.Hosts {
+		if !host.Matches(alias) {
+			continue
+		}
+
+		for _, pattern := range host.Patterns {
+			if pattern.String() != "*" {
+				return true
+			}
+		}
+	}
+
+	return a.get(alias, "HostName") != ""
+}
+
+// get returns the first value of key for alias. The parser only reports
+// errors for malformed conditional Includes, which ssh ignores too.
+func (a sshAlias) get(alias, key string) string {
+	val, _ := a.cfg.Get(alias, key)
+	return val
+}
+
+// endpoint returns the HostName, Port and User configured for alias. The
+// user is "" if none is set.
+func (a sshAlias) endpoint(alias string) (string, int, string, error) {
+	host := a.get(alias, "HostName")
+
+	if host == "" {
+		host = alias
+	}
+
+	// HostName may reference the alias, e.g. "%h.internal".
+	host = strings.ReplaceAll(host, "%h", alias)
+	port := 22
+
+	if p := a.get(alias, "Port"); p != "" {
+		n, err := strconv.Atoi(p)
+
+		if err != nil {
+			return "", 0, "", fmt.Errorf("SSH config for %s: invalid Port %q", alias, p)
+		}
+
+		port = n
+	}
+
+	return host, port, a.get(alias, "User"), nil
+}
+
+// hop resolves one ProxyJump entry, [user@]host[:port], where host may
+// itself be an alias. A ProxyJump set on the hop is not followed.
+func (a sshAlias) hop(spec string) (SSHHop, error) {
+	spec = strings.TrimPrefix(spec, "ssh://")
+	var specUser, specPort string
+
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		specUser, spec = spec[:at], spec[at+1:]
+	}
+
+	if h, p, err := net.SplitHostPort(spec); err == nil {
+		spec, specPort = h, p
+	}
+
+	if spec == "" {
+		return SSHHop{}, errors.New("empty host")
+	}
+
+	host, port, hopUser, err := a.endpoint(spec)
+
+	if err != nil {
+		return SSHHop{}, err
+	}
+
+	if specPort != "" {
+		port, err = strconv.Atoi(specPort)
+
+		if err != nil {
+			return SSHHop{}, fmt.Errorf("invalid port %q", specPort)
+		}
+	}
+
+	if specUser != "" {
+		hopUser = specUser
+	}
+
+	return SSHHop{Host: host, Port: port, User: hopUser}, nil
+}
+
+// identityFile returns the first IdentityFile that exists and parses.
+// Passphrase-protected keys are skipped when ssh-agent is available, on
+// the assumption that it holds them, and are an error otherwise.
+func (a sshAlias) identityFile(alias, host, remoteUser string, useAgent bool) (string, error) {
+	files, _ := a.cfg.GetAll(alias, "IdentityFile")
+
+	if len(files) == 0 {
+		files = defaultIdentityFiles
+	}
+
+	var encrypted string
+
+	for _, file := range files {
+		path := a.expand(file, host, remoteUser)
+		key, err := os.ReadFile(path)
+
+		if err != nil {
+			continue
+		}
+
+		_, err = ssh.ParsePrivateKey(key)
+
+		var missing *ssh.PassphraseMissingError
+
+		switch {
+		case err == nil:
+			return path, nil
+		case errors.As(err, &missing):
+			if encrypted == "" {
+				encrypted = path
+			}
+		default:
+			return "", fmt.Errorf("%w %s: %w", ErrSSHKeyParse, path, err)
+		}
+	}
+
+	if encrypted != "" && !useAgent {
+		return "", fmt.Errorf("%w %s: key is passphrase-protected, add it to ssh-agent", ErrSSHKeyParse, encrypted)
+	}
+
+	return "", nil
+}
+
+// certificateFile returns the contents of the first CertificateFile that
+// exists, or of the key's -cert.pub companion, which ssh also loads.
+func (a sshAlias) certificateFile(alias, key, host, remoteUser string) string {
+	files, _ := a.cfg.GetAll(alias, "CertificateFile")
+
+	if len(files) == 0 {
+		files = []string{key + "-cert.pub"}
+	}
+
+	for _, file := range files {
+		cert, err := os.ReadFile(a.expand(file, host, remoteUser))
+
+		if err == nil {
+			return string(cert)
+		}
+	}
+
+	return ""
+}
+
+// expand resolves a leading "~/" and the %d, %h, %r, %u and %% tokens ssh
+// accepts in file paths.
+func (a sshAlias) expand(path, host, remoteUser string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		path = filepath.Join(a.home, rest)
+	}
+
+	return strings.NewReplacer(
+		"%%", "%",
+		"%d", a.home,
+		"%h", host,
+		"%r", remoteUser,
+		"%u", a.localUser,
+	).Replace(path)
+}
+
+func localUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
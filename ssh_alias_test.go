@@ -0,0 +1,208 @@
+package geb
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	sshconfig "github.com/kevinburke/ssh_config"
+	"golang.org/x/crypto/ssh"
+)
+
+// writeKey writes a new ed25519 private key to path, encrypted if
+// passphrase is set.
+func writeKey(t *testing.T, path, passphrase string) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, "")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSSHAliasResolve(t *testing.T) {
+	home := t.TempDir()
+	dotSSH := filepath.Join(home, ".ssh")
+	if err := os.Mkdir(dotSSH, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, filepath.Join(dotSSH, "id_plain"), "")
+	writeKey(t, filepath.Join(dotSSH, "id_locked"), "hunter2")
+	if err := os.WriteFile(filepath.Join(dotSSH, "known_hosts_work"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	const config = `
+Host bastion
+  HostName bastion.example.com
+  Port 2222
+  User jump
+
+Host inner
+  HostName 10.0.0.5
+
+Host db
+  HostName %h.internal
+  Port 2200
+  User deploy
+  IdentityFile ~/.ssh/id_missing
+  IdentityFile ~/.ssh/id_plain
+  UserKnownHostsFile ~/.ssh/known_hosts_work ~/.ssh/known_hosts_other
+
+Host plain
+  HostName plain.example.com
+
+Host chain
+  HostName chain.internal
+  ProxyJump bastion,ops@inner:2022,edge.example.com
+
+Host proxied
+  HostName proxied.internal
+  ProxyCommand nc %h %p
+
+Host locked-first
+  HostName locked.internal
+  IdentityFile ~/.ssh/id_locked
+  IdentityFile ~/.ssh/id_plain
+
+Host locked-only
+  HostName locked.internal
+  IdentityFile ~/.ssh/id_locked
+
+Host bad-port
+  Port ssh
+`
+	cfg, err := sshconfig.Decode(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := sshAlias{cfg: cfg, home: home, localUser: "local"}
+
+	tests := []struct {
+		alias   string
+		agent   bool
+		want    ConnectViaSSHConfig
+		wantErr string
+	}{
+		{
+			alias: "db",
+			want: ConnectViaSSHConfig{
+				SSHHost:           "db.internal",
+				SSHPort:           2200,
+				SSHUser:           "deploy",
+				SSHPrivateKeyPath: filepath.Join(dotSSH, "id_plain"),
+				KnownHostsPath:    filepath.Join(dotSSH, "known_hosts_work"),
+			},
+		},
+		{
+			alias: "plain",
+			want:  ConnectViaSSHConfig{SSHHost: "plain.example.com", SSHPort: 22, SSHUser: "local"},
+		},
+		{
+			alias: "chain",
+			want: ConnectViaSSHConfig{
+				SSHHost: "chain.internal",
+				SSHPort: 22,
+				SSHUser: "local",
+				Jumps: []SSHHop{
+					{Host: "bastion.example.com", Port: 2222, User: "jump"},
+					{Host: "10.0.0.5", Port: 2022, User: "ops"},
+					{Host: "edge.example.com", Port: 22},
+				},
+			},
+		},
+		{
+			alias:   "proxied",
+			wantErr: "ProxyCommand is not supported",
+		},
+		{
+			alias: "locked-first",
+			want: ConnectViaSSHConfig{
+				SSHHost:           "locked.internal",
+				SSHPort:           22,
+				SSHUser:           "local",
+				SSHPrivateKeyPath: filepath.Join(dotSSH, "id_plain"),
+			},
+		},
+		{
+			alias:   "locked-only",
+			wantErr: "key is passphrase-protected, add it to ssh-agent",
+		},
+		{
+			alias: "locked-only",
+			agent: true,
+			want: ConnectViaSSHConfig{
+				SSHHost:     "locked.internal",
+				SSHPort:     22,
+				SSHUser:     "local",
+				SSHUseAgent: true,
+			},
+		},
+		{
+			alias:   "bad-port",
+			wantErr: `invalid Port "ssh"`,
+		},
+		{
+			alias:   "unknown",
+			wantErr: ErrSSHAliasNotFound.Error(),
+		},
+	}
+	for _, tt := range tests {
+		name := tt.alias
+		if tt.agent {
+			name += "/agent"
+		}
+		t.Run(name, func(t *testing.T) {
+			sock := ""
+			if tt.agent {
+				sock = filepath.Join(home, "agent.sock")
+			}
+			t.Setenv("SSH_AUTH_SOCK", sock)
+
+			got, err := a.resolve(tt.alias, ConnectConfig{DBHost: "127.0.0.1", DBPort: 5432})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolve = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SSHHost != tt.want.SSHHost || got.SSHPort != tt.want.SSHPort || got.SSHUser != tt.want.SSHUser {
+				t.Errorf("endpoint = %s@%s:%d, want %s@%s:%d", got.SSHUser, got.SSHHost, got.SSHPort, tt.want.SSHUser, tt.want.SSHHost, tt.want.SSHPort)
+			}
+			if !slices.Equal(got.Jumps, tt.want.Jumps) {
+				t.Errorf("Jumps = %+v, want %+v", got.Jumps, tt.want.Jumps)
+			}
+			if got.SSHPrivateKeyPath != tt.want.SSHPrivateKeyPath {
+				t.Errorf("SSHPrivateKeyPath = %q, want %q", got.SSHPrivateKeyPath, tt.want.SSHPrivateKeyPath)
+			}
+			if got.KnownHostsPath != tt.want.KnownHostsPath {
+				t.Errorf("KnownHostsPath = %q, want %q", got.KnownHostsPath, tt.want.KnownHostsPath)
+			}
+			if got.SSHUseAgent != tt.want.SSHUseAgent {
+				t.Errorf("SSHUseAgent = %v, want %v", got.SSHUseAgent, tt.want.SSHUseAgent)
+			}
+			if got.DBHost != "127.0.0.1" || got.DBPort != 5432 {
+				t.Errorf("database side = %s:%d, want it taken from conf", got.DBHost, got.DBPort)
+			}
+		})
+	}
+}
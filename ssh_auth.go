@@ -0,0 +1,156 @@
+package geb
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	ErrIncorrectPassphrase = errors.New("ssh: incorrect private key passphrase")
+	ErrNoSSHAuthMethod     = errors.New("no SSH auth method configured: set SSHPrivateKey, SSHPrivateKeyPath, SSHUseAgent, SSHPassword or SSHKeyboardInteractive")
+	ErrNoSSHAgent          = errors.New("ssh-agent requested but SSH_AUTH_SOCK is not set")
+	ErrSSHCertificate      = errors.New("invalid SSH certificate")
+)
+
+// sshAuthMethods returns the configured auth methods in the order the
+// client tries them: publickey first, then password, then
+// keyboard-interactive. The returned func releases the ssh-agent
+// connection and must be called once the handshake is done.
+func sshAuthMethods(conf ConnectViaSSHConfig) ([]ssh.AuthMethod, func(), error) {
+	var signers []ssh.Signer
+	signer, err := privateKeySigner(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if signer != nil {
+		signers = append(signers, signer)
+	}
+
+	release := func() {}
+	var agentClient agent.ExtendedAgent
+	if conf.SSHUseAgent {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, nil, ErrNoSSHAgent
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connect to ssh-agent: %w", err)
+		}
+		agentClient = agent.NewClient(conn)
+		release = func() { conn.Close() }
+	}
+
+	var methods []ssh.AuthMethod
+	// The client tries each method name once, so key and agent signers
+	// have to be offered through a single publickey method.
+	if len(signers) > 0 || agentClient != nil {
+		methods = append(methods, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			if agentClient == nil {
+				return signers, nil
+			}
+			agentSigners, err := agentClient.Signers()
+			if err != nil {
+				return nil, err
+			}
+			return append(signers, agentSigners...), nil
+		}))
+	}
+	if conf.SSHPassword != "" {
+		methods = append(methods, ssh.Password(conf.SSHPassword))
+	}
+	if conf.SSHKeyboardInteractive != nil {
+		// Only called if the server offers keyboard-interactive, either
+		// after the methods above were rejected or as a required second
+		// factor.
+		methods = append(methods, ssh.KeyboardInteractive(conf.SSHKeyboardInteractive))
+	}
+	if len(methods) == 0 {
+		return nil, nil, ErrNoSSHAuthMethod
+	}
+	return methods, release, nil
+}
+
+// privateKeySigner parses SSHPrivateKey or the file at SSHPrivateKeyPath,
+// returning nil if neither is set. With SSHCertificate, the signer presents
+// the certificate instead of the bare key.
+func privateKeySigner(conf ConnectViaSSHConfig) (ssh.Signer, error) {
+	signer, err := keySigner(conf)
+	if err != nil || signer == nil || conf.SSHCertificate == "" {
+		return signer, err
+	}
+	return certSigner(conf.SSHCertificate, signer, time.Now())
+}
+
+func keySigner(conf ConnectViaSSHConfig) (ssh.Signer, error) {
+	switch {
+	case conf.SSHPrivateKey != "":
+		signer, err := parsePrivateKey(conf.SSHPrivateKey, conf.SSHPrivateKeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSSHKeyParse, err)
+		}
+		return signer, nil
+	case conf.SSHPrivateKeyPath != "":
+		key, err := os.ReadFile(conf.SSHPrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w %s: %w", ErrSSHKeyParse, conf.SSHPrivateKeyPath, err)
+		}
+		signer, err := parsePrivateKey(string(key), conf.SSHPrivateKeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("%w %s: %w", ErrSSHKeyParse, conf.SSHPrivateKeyPath, err)
+		}
+		return signer, nil
+	}
+	return nil, nil
+}
+
+// certSigner checks that certificate, in authorized_keys format, is a user
+// certificate for signer's key valid at now, so that a wrong or expired
+// one fails before dialing rather than as a generic auth rejection.
+func certSigner(certificate string, signer ssh.Signer, now time.Time) (ssh.Signer, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(certificate))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSSHCertificate, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%w: got a plain %s public key", ErrSSHCertificate, pub.Type())
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("%w: %q is a host certificate", ErrSSHCertificate, cert.KeyId)
+	}
+	if !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, fmt.Errorf("%w: %q was not issued for the private key", ErrSSHCertificate, cert.KeyId)
+	}
+	unix := uint64(now.Unix())
+	if unix < cert.ValidAfter {
+		return nil, fmt.Errorf("%w: %q is not valid before %s", ErrSSHCertificate, cert.KeyId, certTime(cert.ValidAfter))
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && unix >= cert.ValidBefore {
+		return nil, fmt.Errorf("%w: %q expired at %s", ErrSSHCertificate, cert.KeyId, certTime(cert.ValidBefore))
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
+
+func certTime(t uint64) string {
+	return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+}
+
+func parsePrivateKey(key, passphrase string) (ssh.Signer, error) {
+	if passphrase == "" {
+		return ssh.ParsePrivateKey([]byte(key))
+	}
+	signer, err := ssh.ParsePrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
+	if errors.Is(err, x509.IncorrectPasswordError) {
+		return nil, ErrIncorrectPassphrase
+	}
+	return signer, err
+}
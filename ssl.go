@@ -0,0 +1,53 @@
+package geb
+
+import (
+	"os"
+)
+
+// writeSSLFiles writes inline PEM material to temp files and points the
+// matching path fields at them. Inline PEM takes precedence over a path.
+func writeSSLFiles(conf *ConnectConfig) ([]string, error) {
+	var files []string
+	for _, f := range []struct {
+		pem  string
+		path *string
+	}{
+		{conf.SSLRootCertPEM, &conf.SSLRootCert},
+		{conf.SSLCertPEM, &conf.SSLCert},
+		{conf.SSLKeyPEM, &conf.SSLKey},
+	} {
+		if f.pem == "" {
+			continue
+		}
+		name, err := writeTempFile(f.pem)
+		if err != nil {
+			removeFiles(files)
+			return nil, err
+		}
+		files = append(files, name)
+		*f.path = name
+	}
+	return files, nil
+}
+
+func writeTempFile(content string) (string, error) {
+	f, err := os.CreateTemp("", "geb-ssl-*.pem")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func removeFiles(files []string) {
+	for _, name := range files {
+		os.Remove(name)
+	}
+}
@@ -0,0 +1,31 @@
+package geb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WithStatementTimeout returns a handle whose statements run with
+// statement_timeout d instead of the pool's, e.g. for one long report. The
+// handle is a transaction on a connection taken out of the pool, with the
+// timeout applied through SET LOCAL so it ends with the transaction. Call
+// the returned func when done: it rolls the transaction back and returns
+// the connection to the pool, so use the handle for reads only.
+func (pg *PG) WithStatementTimeout(ctx context.Context, d time.Duration) (*gorm.DB, func(), error) {
+	if d <= 0 {
+		return nil, nil, fmt.Errorf("statement timeout must be positive, got %s", d)
+	}
+	tx := pg.GormDB().WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, nil, tx.Error
+	}
+	// SET takes no bind parameters; the value is an integer.
+	if err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", timeoutMillis(d))).Error; err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	return tx, func() { tx.Rollback() }, nil
+}
@@ -0,0 +1,127 @@
+package geb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTxMaxRetries = 3
+	defaultTxBackoff    = 50 * time.Millisecond
+)
+
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	maxRetries int
+	backoff    time.Duration
+	sqlOpts    *sql.TxOptions
+	isolation  *sql.IsolationLevel
+}
+
+// WithMaxRetries sets how many times a transaction failing with a
+// serialization failure or deadlock is retried (default 3).
+func WithMaxRetries(n int) TxOption {
+	return func(o *txOptions) {
+		o.maxRetries = n
+	}
+}
+
+// WithBackoff sets the wait before the first retry, doubled on each
+// subsequent one (default 50ms).
+func WithBackoff(d time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.backoff = d
+	}
+}
+
+func WithTxOptions(opts *sql.TxOptions) TxOption {
+	return func(o *txOptions) {
+		o.sqlOpts = opts
+	}
+}
+
+// WithIsolation runs the transaction at level instead of the config's
+// DefaultIsolation.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.isolation = &level
+	}
+}
+
+// Transaction runs fn in a transaction, retrying it with exponential backoff
+// when Postgres aborts it with SQLSTATE 40001 or 40P01. fn must therefore be
+// safe to run more than once. The last error is returned once retries are
+// exhausted.
+func (pg *PG) Transaction(ctx context.Context, fn func(tx *gorm.DB) error, opts ...TxOption) error {
+	return transaction(ctx, pg.GormDB(), pg.conf.DefaultIsolation, fn, opts)
+}
+
+// Transaction is PG.Transaction over the tunnel, defaulting to
+// ConnectViaSSHConfig.DefaultIsolation.
+func (pg *PGViaSSH) Transaction(ctx context.Context, fn func(tx *gorm.DB) error, opts ...TxOption) error {
+	pg.mu.RLock()
+	defaultIsolation := pg.conf.DefaultIsolation
+	pg.mu.RUnlock()
+
+	return transaction(ctx, pg.GormDB(), defaultIsolation, fn, opts)
+}
+
+func transaction(ctx context.Context, db *gorm.DB, defaultIsolation sql.IsolationLevel, fn func(tx *gorm.DB) error, opts []TxOption) error {
+	o := txOptions{
+		maxRetries: defaultTxMaxRetries,
+		backoff:    defaultTxBackoff,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	sqlOpts := o.resolve(defaultIsolation)
+
+	backoff := o.backoff
+	for attempt := 0; ; attempt++ {
+		err := db.WithContext(ctx).Transaction(fn, sqlOpts)
+		if err == nil || attempt >= o.maxRetries || !isRetryableTxError(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// resolve picks the isolation level: WithIsolation first, then an
+// explicit level in WithTxOptions, then the connection's default.
+func (o txOptions) resolve(defaultIsolation sql.IsolationLevel) *sql.TxOptions {
+	level := defaultIsolation
+	if o.sqlOpts != nil && o.sqlOpts.Isolation != sql.LevelDefault {
+		level = o.sqlOpts.Isolation
+	}
+	if o.isolation != nil {
+		level = *o.isolation
+	}
+	if level == sql.LevelDefault {
+		return o.sqlOpts
+	}
+	opts := sql.TxOptions{Isolation: level}
+	if o.sqlOpts != nil {
+		opts.ReadOnly = o.sqlOpts.ReadOnly
+	}
+	return &opts
+}
+
+func isRetryableTxError(err error) bool {
+	switch pgErrorCode(err) {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package geb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// isolationRecorder opens connections that accept every statement, like
+// nopConnector's, and record the isolation level of each transaction.
+type isolationRecorder struct {
+	levels *[]sql.IsolationLevel
+}
+
+func (r isolationRecorder) Connect(context.Context) (driver.Conn, error) {
+	return isolationConn{levels: r.levels}, nil
+}
+
+func (isolationRecorder) Driver() driver.Driver { return nil }
+
+type isolationConn struct {
+	nopConn
+	levels *[]sql.IsolationLevel
+}
+
+func (c isolationConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	*c.levels = append(*c.levels, sql.IsolationLevel(opts.Isolation))
+	return nopConn{}, nil
+}
+
+func TestTransactionDefaultIsolation(t *testing.T) {
+	var levels []sql.IsolationLevel
+	sqlDB := sql.OpenDB(isolationRecorder{&levels})
+	defer sqlDB.Close()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), gormConfig(ConnectConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clients := map[string]interface {
+		Transaction(context.Context, func(*gorm.DB) error, ...TxOption) error
+	}{
+		"PG":       &PG{DB: db, conf: ConnectConfig{DefaultIsolation: sql.LevelRepeatableRead}},
+		"PGViaSSH": &PGViaSSH{DB: db, conf: ConnectViaSSHConfig{DefaultIsolation: sql.LevelRepeatableRead}},
+	}
+	for name, client := range clients {
+		t.Run(name, func(t *testing.T) {
+			levels = nil
+			ctx := context.Background()
+			noop := func(*gorm.DB) error { return nil }
+			if err := client.Transaction(ctx, noop); err != nil {
+				t.Fatal(err)
+			}
+			if err := client.Transaction(ctx, noop, WithIsolation(sql.LevelSerializable)); err != nil {
+				t.Fatal(err)
+			}
+			if len(levels) != 2 || levels[0] != sql.LevelRepeatableRead || levels[1] != sql.LevelSerializable {
+				t.Errorf("transactions began at %v, want [Repeatable Read Serializable]", levels)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package geb
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// tunnelHealth records when the SSH server last answered a keepalive, so
+// TunnelAlive can reuse the keepalive goroutine's results. A nil
+// *tunnelHealth, as in a PGViaSSH built by hand, records nothing.
+type tunnelHealth struct {
+	lastOK atomic.Int64
+}
+
+// probe sends a keepalive and reports whether the server answered before
+// ctx is done. SendRequest can't be interrupted, so a probe that times out
+// leaves its goroutine waiting until the connection answers or closes.
+func (h *tunnelHealth) probe(ctx context.Context, client *ssh.Client) bool {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return false
+		}
+		if h != nil {
+			h.lastOK.Store(time.Now().UnixNano())
+		}
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// answeredWithin reports whether a keepalive succeeded in the last d.
+func (h *tunnelHealth) answeredWithin(d time.Duration) bool {
+	if h == nil {
+		return false
+	}
+	last := h.lastOK.Load()
+	return last != 0 && time.Since(time.Unix(0, last)) < d
+}
+
+// TunnelAlive reports whether the SSH server answers a keepalive before ctx
+// is done, independently of the database. With SSHKeepAliveInterval set,
+// a keepalive answered within the last interval counts without sending
+// another one.
+func (pg *PGViaSSH) TunnelAlive(ctx context.Context) bool {
+	pg.mu.RLock()
+	client, health, interval := pg.SSHCon, pg.health, pg.conf.SSHKeepAliveInterval
+	pg.mu.RUnlock()
+
+	if interval > 0 && health.answeredWithin(interval) {
+		return true
+	}
+
+	return health.probe(ctx, client)
+}
@@ -0,0 +1,79 @@
+package geb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type UpsertOption func(*upsertOptions)
+
+type upsertOptions struct {
+	doNothing bool
+}
+
+// OnConflictDoNothing makes Upsert skip conflicting rows, leaving the
+// existing ones untouched, instead of updating them.
+func OnConflictDoNothing() UpsertOption {
+	return func(o *upsertOptions) {
+		o.doNothing = true
+	}
+}
+
+// Upsert inserts value, a struct or slice of structs, with
+// INSERT ... ON CONFLICT (conflictColumns) DO UPDATE SET updateColumns.
+// conflictColumns must match a unique index or constraint. An empty
+// updateColumns updates every other column except the primary key and
+// autoCreateTime ones, so created_at keeps the original insert time.
+// Columns are database names, e.g. "email" rather than "Email".
+func (pg *PG) Upsert(ctx context.Context, value interface{}, conflictColumns []string, updateColumns []string, opts ...UpsertOption) error {
+	var o upsertOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(conflictColumns) == 0 && !o.doNothing {
+		return errors.New("upsert: conflictColumns is required unless OnConflictDoNothing is set")
+	}
+
+	db := pg.GormDB().WithContext(ctx)
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(value); err != nil {
+		return err
+	}
+	for _, column := range append(slices.Clone(conflictColumns), updateColumns...) {
+		if _, ok := stmt.Schema.FieldsByDBName[column]; !ok {
+			return fmt.Errorf("upsert: %s has no column %q", stmt.Schema.Table, column)
+		}
+	}
+
+	onConflict := clause.OnConflict{DoNothing: o.doNothing}
+	for _, column := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+	if !o.doNothing {
+		if len(updateColumns) == 0 {
+			updateColumns = upsertColumns(stmt.Schema.Fields, conflictColumns)
+		}
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	}
+	return db.Clauses(onConflict).Create(value).Error
+}
+
+// upsertColumns returns the columns a default Upsert updates.
+func upsertColumns(fields []*schema.Field, conflictColumns []string) []string {
+	var columns []string
+	for _, field := range fields {
+		if field.DBName == "" || field.PrimaryKey || field.AutoCreateTime > 0 || !field.Creatable || !field.Updatable {
+			continue
+		}
+		if !slices.Contains(conflictColumns, field.DBName) {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,221 @@
+package geb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	schemaName = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_$]*|\$user)$`)
+	// Custom parameters take a single dotted prefix, e.g. myapp.tenant.
+	runtimeParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+	// dedicatedParams are the parameters RuntimeParams must not set, as
+	// a config field already does.
+	dedicatedParams = map[string]string{
+		"application_name":                    "AppName",
+		"timezone":                            "TimeZone",
+		"statement_timeout":                   "StatementTimeout",
+		"idle_in_transaction_session_timeout": "IdleInTxTimeout",
+		"search_path":                         "SearchPath",
+		"default_transaction_read_only":       "ReadOnly",
+	}
+
+	isolationLevels = []sql.IsolationLevel{sql.LevelDefault, sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelRepeatableRead, sql.LevelSerializable}
+
+	sslModes           = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	channelBindings    = []string{"disable", "prefer", "require"}
+	targetSessionAttrs = []string{"any", "read-write", "read-only", "primary", "standby", "prefer-standby"}
+
+	// lib/pq has no fallback between TLS and plaintext, so it rejects
+	// allow and prefer at connect time.
+	pqSSLModes = []string{"disable", "require", "verify-ca", "verify-full"}
+)
+
+// Validate reports every problem with the config at once.
+func (conf ConnectConfig) Validate() error {
+	var errs []error
+	if len(conf.Hosts) == 0 {
+		if conf.DBHost == "" {
+			errs = append(errs, errors.New("DBHost is required"))
+		}
+		if err := checkPort("DBPort", conf.DBPort); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i, hp := range conf.Hosts {
+		if hp.Host == "" {
+			errs = append(errs, fmt.Errorf("Hosts[%d].Host is required", i))
+		}
+		if err := checkPort(fmt.Sprintf("Hosts[%d].Port", i), hp.Port); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if conf.Driver == DriverPQ {
+		errs = append(errs, checkPQSupport(conf)...)
+	}
+	if conf.ChannelBinding != "" && !slices.Contains(channelBindings, conf.ChannelBinding) {
+		errs = append(errs, fmt.Errorf("invalid ChannelBinding %q: must be one of %s", conf.ChannelBinding, strings.Join(channelBindings, ", ")))
+	}
+	if conf.ChannelBinding == "require" && conf.SSLMode == "disable" {
+		errs = append(errs, errors.New(`ChannelBinding "require" needs TLS, but SSLMode is "disable"`))
+	}
+	if conf.SOCKS5Proxy != "" && conf.DialFunc != nil {
+		errs = append(errs, errors.New("SOCKS5Proxy and DialFunc are mutually exclusive"))
+	}
+	if conf.TargetSessionAttrs != "" && !slices.Contains(targetSessionAttrs, conf.TargetSessionAttrs) {
+		errs = append(errs, fmt.Errorf("invalid TargetSessionAttrs %q: must be one of %s", conf.TargetSessionAttrs, strings.Join(targetSessionAttrs, ", ")))
+	}
+	if conf.DBUser == "" {
+		errs = append(errs, errors.New("DBUser is required"))
+	}
+	if conf.DBName == "" {
+		errs = append(errs, errors.New("DBName is required"))
+	}
+	if conf.PasswordProvider != nil && conf.DBPassword != "" {
+		errs = append(errs, errors.New("set DBPassword or PasswordProvider, not both"))
+	}
+	if conf.MaxIdleCon < 0 {
+		errs = append(errs, fmt.Errorf("MaxIdleCon must not be negative, got %d", conf.MaxIdleCon))
+	}
+	if conf.MaxOpenConns < 0 {
+		errs = append(errs, fmt.Errorf("MaxOpenConns must not be negative, got %d", conf.MaxOpenConns))
+	}
+	if conf.InstanceCount < 0 {
+		errs = append(errs, fmt.Errorf("InstanceCount must not be negative, got %d", conf.InstanceCount))
+	}
+	if conf.ConnMaxLifetimeJitter < 0 {
+		errs = append(errs, fmt.Errorf("ConnMaxLifetimeJitter must not be negative, got %s", conf.ConnMaxLifetimeJitter))
+	}
+	if conf.ConnMaxLifetimeJitter > 0 && conf.ConnMaxLifetime <= 0 {
+		errs = append(errs, errors.New("ConnMaxLifetimeJitter requires ConnMaxLifetime"))
+	}
+	if !slices.Contains(isolationLevels, conf.DefaultIsolation) {
+		errs = append(errs, fmt.Errorf("unsupported DefaultIsolation %s: Postgres supports read uncommitted, read committed, repeatable read and serializable", conf.DefaultIsolation))
+	}
+	if conf.DefaultQueryTimeout < 0 {
+		errs = append(errs, fmt.Errorf("DefaultQueryTimeout must not be negative, got %s", conf.DefaultQueryTimeout))
+	}
+	if conf.SSLMode != "" && !slices.Contains(sslModes, conf.SSLMode) {
+		errs = append(errs, fmt.Errorf("invalid SSLMode %q: must be one of %s", conf.SSLMode, strings.Join(sslModes, ", ")))
+	}
+	if err := checkTimeZone(conf.TimeZone); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.SkipTimeZone && conf.TimeZone != "" {
+		errs = append(errs, errors.New("SkipTimeZone and TimeZone are mutually exclusive"))
+	}
+	if conf.Driver != "" && !slices.Contains(drivers, conf.Driver) {
+		errs = append(errs, fmt.Errorf("invalid Driver %q: must be one of %s", conf.Driver, strings.Join(drivers, ", ")))
+	}
+	if conf.SearchPath != "" {
+		for _, schema := range splitSearchPath(conf.SearchPath) {
+			if !schemaName.MatchString(schema) {
+				errs = append(errs, fmt.Errorf("invalid SearchPath schema %q: must be an unquoted identifier or $user", schema))
+			}
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(conf.RuntimeParams)) {
+		if !runtimeParamName.MatchString(key) {
+			errs = append(errs, fmt.Errorf("invalid RuntimeParams key %q: must be a parameter name such as lock_timeout or myapp.tenant", key))
+		} else if field, ok := dedicatedParams[strings.ToLower(key)]; ok {
+			errs = append(errs, fmt.Errorf("RuntimeParams key %q: set %s instead", key, field))
+		}
+	}
+	for i, replica := range conf.ReadReplicas {
+		if err := conf.replicaConfig(replica).Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("ReadReplicas[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every problem with the config at once, including those
+// of the database settings.
+func (conf ConnectViaSSHConfig) Validate() error {
+	var errs []error
+	if conf.SSHHost == "" {
+		errs = append(errs, errors.New("SSHHost is required"))
+	}
+	if err := checkPort("SSHPort", conf.SSHPort); err != nil {
+		errs = append(errs, err)
+	}
+	if conf.SSHUser == "" {
+		errs = append(errs, errors.New("SSHUser is required"))
+	}
+	if conf.SSHPrivateKey == "" && conf.SSHPrivateKeyPath == "" && !conf.SSHUseAgent && conf.SSHPassword == "" && conf.SSHKeyboardInteractive == nil {
+		errs = append(errs, ErrNoSSHAuthMethod)
+	}
+	if conf.SSHCertificate != "" && conf.SSHPrivateKey == "" && conf.SSHPrivateKeyPath == "" {
+		errs = append(errs, errors.New("SSHCertificate requires SSHPrivateKey or SSHPrivateKeyPath"))
+	}
+	if conf.SSHDialTimeout < 0 {
+		errs = append(errs, fmt.Errorf("SSHDialTimeout must not be negative, got %s", conf.SSHDialTimeout))
+	}
+	if conf.SSHHostKeyCallback == nil && conf.KnownHostsPath == "" && conf.SSHHostKeyFingerprint == "" && !conf.InsecureSkipHostKeyVerify {
+		errs = append(errs, ErrNoHostKeyVerification)
+	}
+	errs = append(errs, checkTunneledDriver(conf.connectConfig())...)
+	for i, hop := range conf.Jumps {
+		if hop.Host == "" {
+			errs = append(errs, fmt.Errorf("Jumps[%d].Host is required", i))
+		}
+		if err := checkPort(fmt.Sprintf("Jumps[%d].Port", i), hop.Port); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := conf.connectConfig().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// checkPQSupport reports the settings lib/pq doesn't support. Unknown
+// SSLModes are reported by Validate on their own.
+func checkPQSupport(conf ConnectConfig) []error {
+	var errs []error
+	if len(conf.Hosts) > 0 || conf.TargetSessionAttrs != "" {
+		errs = append(errs, errors.New("Hosts and TargetSessionAttrs require the pgx driver"))
+	}
+	if conf.ChannelBinding != "" {
+		errs = append(errs, errors.New("ChannelBinding requires the pgx driver"))
+	}
+	if conf.DialFunc != nil {
+		errs = append(errs, errors.New("DialFunc requires the pgx driver"))
+	}
+	if conf.SOCKS5Proxy != "" {
+		errs = append(errs, errors.New("SOCKS5Proxy requires the pgx driver"))
+	}
+	if slices.Contains(sslModes, conf.SSLMode) && !slices.Contains(pqSSLModes, conf.SSLMode) {
+		errs = append(errs, fmt.Errorf("SSLMode %q is not supported by lib/pq: use one of %s, or the pgx driver", conf.SSLMode, strings.Join(pqSSLModes, ", ")))
+	}
+	return errs
+}
+
+// checkTunneledDriver reports what Validate misses for a database opened
+// through an SSH tunnel, where an empty Driver means lib/pq rather than
+// pgx, and where the tunnel dials every connection itself.
+func checkTunneledDriver(conf ConnectConfig) []error {
+	var errs []error
+	if conf.DialFunc != nil || conf.SOCKS5Proxy != "" {
+		errs = append(errs, errors.New("DialFunc and SOCKS5Proxy are not supported through an SSH tunnel"))
+		conf.DialFunc, conf.SOCKS5Proxy = nil, ""
+	}
+	if conf.Driver == "" {
+		conf.Driver = DriverPQ
+		errs = append(errs, checkPQSupport(conf)...)
+	}
+	return errs
+}
+
+func checkPort(field string, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("%s must be between 1 and 65535, got %d", field, port)
+	}
+	return nil
+}